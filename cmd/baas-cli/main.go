@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/integrail/baas-client/pkg/client/dto"
@@ -10,12 +11,20 @@ import (
 	"github.com/integrail/baas-client/pkg/client"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/integrail/baas-client/internal/build"
 )
 
 func main() {
+	err := newRootCmd().Execute()
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newRootCmd() *cobra.Command {
 	var cfg client.Config
 	cfg.Url = "https://baas.integrail.ai"
 	if os.Getenv("BAAS_URL") != "" {
@@ -30,12 +39,13 @@ func main() {
 
 	var cookiesSlice []string
 	var cookieDomain string
+	var verbose, jsonEvents bool
 	rootCmd := &cobra.Command{
 		Use:     "baas",
 		Version: build.Version,
 		Short:   "BaaS is a Browser as a Service",
 		Long:    "Easy way to control chrome browser within AWS Lambda",
-		Run: func(cmd *cobra.Command, args []string) {
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			for k, v := range util.SliceToMap(cookiesSlice) {
 				cfg.Cookies = append(cfg.Cookies, dto.BrowserCookie{
 					Name:   k,
@@ -44,7 +54,9 @@ func main() {
 					Path:   "/",
 				})
 			}
-			startBaasClient(cfg)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			startBaasClient(cfg, verboseReporter(verbose, jsonEvents))
 		},
 	}
 	rootCmd.PersistentFlags().StringVarP(&cfg.Url, "url", "u", cfg.Url, "BaaS backend URL")
@@ -56,16 +68,40 @@ func main() {
 	rootCmd.PersistentFlags().StringSliceVarP(&cfg.Secrets, "secret", "S", []string{}, "Secrets to send to backend with each async request")
 	rootCmd.PersistentFlags().StringSliceVarP(&cfg.Values, "value", "V", []string{}, "Values to send to backend with each async request")
 	rootCmd.PersistentFlags().StringSliceVarP(&cookiesSlice, "cookie", "C", []string{}, "Cookies to send to backend with each async request")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.Tags, "tag", []string{}, "Tags (key=value) to label the session with in the backend")
+	rootCmd.PersistentFlags().StringVarP(&cfg.OutDir, "out-dir", "o", "", "Directory to save screenshots, downloaded files and session transcript to (default: temp dir)")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print every command, result, duration and cost to stderr")
+	rootCmd.PersistentFlags().BoolVar(&jsonEvents, "json", false, "Print verbose output as JSON lines")
 	rootCmd.PersistentFlags().StringVarP(&cookieDomain, "cookie-domain", "D", "", "Cookies domain to set with cookies backend with each async request")
 
-	err := rootCmd.Execute()
-	if err != nil {
-		panic(err)
+	var compare bool
+	replayCmd := &cobra.Command{
+		Use:   "replay <transcript.ndjson>",
+		Short: "Replay programs recorded in the session transcript against a fresh session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reporter := verboseReporter(verbose, jsonEvents)
+			if reporter == nil {
+				reporter = &quietReporter{}
+			}
+			return replayTranscript(cmd.Context(), cfg, reporter, args[0], compare)
+		},
 	}
+	replayCmd.Flags().BoolVarP(&compare, "compare", "c", false, "Check whether result of each step matches the recorded one")
+	rootCmd.AddCommand(replayCmd)
+
+	return rootCmd
 }
 
-func startBaasClient(cfg client.Config) {
-	client, err := client.BubbleClient(context.Background(), cfg)
+func verboseReporter(verbose, jsonEvents bool) client.Reporter {
+	if !verbose {
+		return nil
+	}
+	return client.NewVerboseReporter(os.Stderr, jsonEvents)
+}
+
+func startBaasClient(cfg client.Config, reporter client.Reporter) {
+	client, err := client.BubbleClientWithReporter(context.Background(), cfg, reporter)
 	if err != nil {
 		panic(err)
 	}
@@ -75,3 +111,33 @@ func startBaasClient(cfg client.Config) {
 		panic(err)
 	}
 }
+
+type quietReporter struct{}
+
+func (r *quietReporter) Report(msg string) {}
+
+func replayTranscript(ctx context.Context, cfg client.Config, reporter client.Reporter, fileName string, compare bool) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open transcript")
+	}
+	defer file.Close()
+	entries, err := client.ReadTranscript(file)
+	if err != nil {
+		return err
+	}
+
+	secrets := util.SliceToMap(cfg.Secrets)
+	p, err := client.NewProgram(ctx, cfg, reporter,
+		client.WithSecrets(secrets),
+		client.WithValues(util.SliceToMap(cfg.Values)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to start session")
+	}
+	steps, err := client.Replay(p, entries, secrets, compare)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Successfully replayed %d steps\n", steps)
+	return nil
+}
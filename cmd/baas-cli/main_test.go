@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTagFlagAccumulates(t *testing.T) {
+	RegisterTestingT(t)
+
+	cmd := newRootCmd()
+	Expect(cmd.ParseFlags([]string{"--tag", "job=nightly", "--tag", "tenant=acme"})).To(Succeed())
+	tags, err := cmd.PersistentFlags().GetStringSlice("tag")
+	Expect(err).To(BeNil())
+	Expect(tags).To(Equal([]string{"job=nightly", "tenant=acme"}))
+}
@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -17,10 +19,27 @@ import (
 	"github.com/integrail/baas-client/pkg/client/dto"
 )
 
+const (
+	DefaultConnectTimeout  = 10 * time.Second
+	DefaultResponseTimeout = 30 * time.Second
+)
+
 type baasClient struct {
-	baasURL    string
-	baasApiKey string
-	timeout    time.Duration
+	baasURL         string
+	baasApiKey      string
+	httpClient      *http.Client
+	responseTimeout time.Duration
+
+	requestIDsMu sync.Mutex
+	requestIDs   map[string]*sessionRequestIDs // recent request IDs used within each running session
+}
+
+// maxRequestIDsPerSession limits how many recent request IDs are remembered per session to reject duplicates
+const maxRequestIDsPerSession = 1000
+
+type sessionRequestIDs struct {
+	used  map[string]bool
+	order []string // oldest first
 }
 
 type Meta struct {
@@ -29,30 +48,56 @@ type Meta struct {
 	JobUID    string  `json:"jobUID" yaml:"jobUID"`                           // unique identifier of job for debugging purposes
 }
 
+// WaitFunc blocks until the async session stream ends or ctx is cancelled.
+// When ctx is cancelled the stream is kept open (unless WithCloseOnCancel is passed),
+// so that waiting can be resumed later by calling WaitFunc again.
+// Returns error if the backend reported an error during the session (the stream is closed right after it)
+type WaitFunc func(ctx context.Context, opts ...WaitOption) error
+
+type WaitOption func(o *waitOpts)
+
+type waitOpts struct {
+	closeOnCancel bool
+}
+
+// WithCloseOnCancel makes WaitFunc close the session stream when ctx is cancelled
+func WithCloseOnCancel() WaitOption {
+	return func(o *waitOpts) {
+		o.closeOnCancel = true
+	}
+}
+
 type Client interface {
-	RunAsync(ctx context.Context, baasRequest dto.Config) (*dto.BrowserMessageOut, func(), error)
+	RunAsync(ctx context.Context, baasRequest dto.Config) (*dto.BrowserMessageOut, WaitFunc, error)
 	Message(ctx context.Context, message dto.BrowserMessageIn) (*dto.BrowserMessageOut, error)
+	FetchScreenshot(ctx context.Context, url string) ([]byte, error)
 }
 
-func NewClient(baasURL, baasKey string, timeout time.Duration) Client {
+// NewClient creates BaaS client, connectTimeout limits establishing connection to the backend,
+// responseTimeout limits receiving full response to a message (unless message specifies its own timeout),
+// async session streams are limited only by their context
+func NewClient(baasURL, baasKey string, connectTimeout, responseTimeout time.Duration) Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
 	return &baasClient{
-		baasURL:    baasURL,
-		baasApiKey: baasKey,
-		timeout:    timeout,
+		baasURL:         baasURL,
+		baasApiKey:      baasKey,
+		httpClient:      &http.Client{Transport: transport},
+		responseTimeout: responseTimeout,
+		requestIDs:      map[string]*sessionRequestIDs{},
 	}
 }
 
-func (o *baasClient) runClient(ctx context.Context, headers map[string]string, endpoint string, timeout string, body any) (*http.Response, error) {
-	timeoutDuration := o.timeout
+func (o *baasClient) messageTimeout(timeout string) time.Duration {
 	if dur, err := time.ParseDuration(timeout); err != nil {
-		// nothing to do
+		return o.responseTimeout
 	} else {
-		timeoutDuration = dur
+		return dur
 	}
+}
 
-	// Fetch page from URL
-	client := &http.Client{Timeout: timeoutDuration}
-
+func (o *baasClient) runClient(ctx context.Context, headers map[string]string, endpoint string, body any) (*http.Response, error) {
 	baasURL := fmt.Sprintf("%s%s", o.baasURL, endpoint)
 
 	reqBodyBytes, err := json.Marshal(body)
@@ -69,7 +114,7 @@ func (o *baasClient) runClient(ctx context.Context, headers map[string]string, e
 		req.Header.Add(k, v)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := o.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch the page: %v", err)
 	}
@@ -79,11 +124,22 @@ func (o *baasClient) runClient(ctx context.Context, headers map[string]string, e
 	return resp, nil
 }
 
+// Message sends program to the session, msg.RequestID can be set to correlate the message across systems
+// (it must be unique within the session), otherwise random request ID is generated
 func (o *baasClient) Message(ctx context.Context, msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
-	// generate random request ID
-	msg.RequestID = lo.RandomString(20, lo.LettersCharset)
-	resp, err := o.runClient(ctx, map[string]string{}, "/api/async/message", msg.Timeout, msg)
+	if msg.RequestID == "" {
+		msg.RequestID = lo.RandomString(20, lo.LettersCharset)
+	}
+	if err := o.registerRequestID(msg.SessionID, msg.RequestID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.messageTimeout(msg.Timeout))
+	defer cancel()
+	resp, err := o.runClient(ctx, map[string]string{}, "/api/async/message", msg)
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = &CommandError{Message: err.Error(), Kinds: []error{ErrTimeout}}
+		}
 		return nil, errors.Wrapf(err, "failed to make baas request")
 	}
 	var baasResponseObjects []dto.BrowserMessageOut
@@ -94,7 +150,10 @@ func (o *baasClient) Message(ctx context.Context, msg dto.BrowserMessageIn) (*dt
 	}
 	respBytes = []byte("[" + string(respBytes) + "]")
 
-	err = json.Unmarshal(respBytes, &baasResponseObjects)
+	// decode numbers as json.Number to preserve large integers
+	decoder := json.NewDecoder(bytes.NewReader(respBytes))
+	decoder.UseNumber()
+	err = decoder.Decode(&baasResponseObjects)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal baas response: %s", string(respBytes))
 	}
@@ -107,15 +166,70 @@ func (o *baasClient) Message(ctx context.Context, msg dto.BrowserMessageIn) (*dt
 	}
 
 	if lo.FromPtr(baasResponse.Meta.Error) != "" {
-		return nil, errors.Errorf("baas returned error: %s, baas RequestUID: %q", lo.FromPtr(baasResponse.Meta.Error), baasResponse.Meta.RequestUID)
+		return nil, errors.Wrapf(newCommandError(lo.FromPtr(baasResponse.Meta.Error)), "baas returned error (baas RequestUID: %q)", baasResponse.Meta.RequestUID)
 	}
 	return &baasResponse, nil
 }
 
-func (o *baasClient) RunAsync(ctx context.Context, baasRequest dto.Config) (*dto.BrowserMessageOut, func(), error) {
+// FetchScreenshot downloads screenshot delivered by URL (see dto.ScreenshotDeliveryURL),
+// API key is only sent if the URL points to the BaaS backend
+func (o *baasClient) FetchScreenshot(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.responseTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to init screenshot request")
+	}
+	if strings.HasPrefix(url, o.baasURL+"/") {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", o.baasApiKey))
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch screenshot")
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch screenshot: status code %d: %s", resp.StatusCode, string(readBytes(resp.Body)))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read screenshot")
+	}
+	return data, nil
+}
+
+// registerRequestID rejects request ID already used within the session among its last maxRequestIDsPerSession ones
+func (o *baasClient) registerRequestID(sessionID, requestID string) error {
+	o.requestIDsMu.Lock()
+	defer o.requestIDsMu.Unlock()
+	ids := o.requestIDs[sessionID]
+	if ids == nil {
+		ids = &sessionRequestIDs{used: map[string]bool{}}
+		o.requestIDs[sessionID] = ids
+	}
+	if ids.used[requestID] {
+		return errors.Errorf("request ID %q has already been used within session %q", requestID, sessionID)
+	}
+	ids.used[requestID] = true
+	ids.order = append(ids.order, requestID)
+	if len(ids.order) > maxRequestIDsPerSession {
+		delete(ids.used, ids.order[0])
+		ids.order = ids.order[1:]
+	}
+	return nil
+}
+
+// forgetSession drops request IDs of the session once it has ended
+func (o *baasClient) forgetSession(sessionID string) {
+	o.requestIDsMu.Lock()
+	defer o.requestIDsMu.Unlock()
+	delete(o.requestIDs, sessionID)
+}
+
+func (o *baasClient) RunAsync(ctx context.Context, baasRequest dto.Config) (*dto.BrowserMessageOut, WaitFunc, error) {
 	resp, err := o.runClient(ctx, map[string]string{
 		"Accept": "text/event-stream",
-	}, "/api/async/start", baasRequest.Browser.Timeout, baasRequest)
+	}, "/api/async/start", baasRequest)
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "failed to make baas request")
 	}
@@ -139,15 +253,81 @@ func (o *baasClient) RunAsync(ctx context.Context, baasRequest dto.Config) (*dto
 	if lo.FromPtr(baasResponse.Meta.Error) != "" {
 		return nil, nil, errors.Errorf("baas returned error: %s, baas RequestUID: %q", lo.FromPtr(baasResponse.Meta.Error), baasResponse.Meta.RequestUID)
 	}
-	return &baasResponse, func() {
+	stream := newAsyncStream(resp.Body, reader)
+	go func() {
+		<-stream.done
+		o.forgetSession(baasResponse.SessionID)
+	}()
+	return &baasResponse, stream.wait, nil
+}
+
+type asyncStream struct {
+	body      io.Closer
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+func newAsyncStream(body io.Closer, reader *bufio.Reader) *asyncStream {
+	s := &asyncStream{
+		body: body,
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		defer s.close()
 		for {
-			_, err := reader.ReadString('\n')
+			line, err := reader.ReadString('\n')
+			if s.err = frameError(line); s.err != nil {
+				return
+			}
 			if err != nil {
-				break
+				return
 			}
 		}
-		resp.Body.Close()
-	}, nil
+	}()
+	return s
+}
+
+// frameError returns error reported by the backend in the streamed frame (if any)
+func frameError(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	var frame dto.BrowserMessageOut
+	if err := json.Unmarshal([]byte(line), &frame); err != nil {
+		return nil
+	}
+	if lo.FromPtr(frame.Meta.Error) != "" {
+		return errors.Errorf("baas returned error: %s, baas RequestUID: %q", lo.FromPtr(frame.Meta.Error), frame.Meta.RequestUID)
+	}
+	if frame.Error != "" {
+		return errors.Errorf("baas returned error: %s", frame.Error)
+	}
+	return nil
+}
+
+func (s *asyncStream) wait(ctx context.Context, opts ...WaitOption) error {
+	var o waitOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		if o.closeOnCancel {
+			s.close()
+		}
+		return nil
+	}
+}
+
+func (s *asyncStream) close() {
+	s.closeOnce.Do(func() {
+		_ = s.body.Close()
+	})
 }
 
 // nolint: unused
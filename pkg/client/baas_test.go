@@ -1,11 +1,20 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
 )
 
 type testObj struct {
@@ -28,3 +37,213 @@ func TestUnmarshalMulti(t *testing.T) {
 	Expect(err).To(BeNil())
 	Expect(objects[len(objects)-1].ID).To(Equal("2"))
 }
+
+func TestRunAsyncWaitCancel(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"sessionID":"test-session"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	res, wait, err := NewClient(server.URL, "key", time.Second, time.Minute).RunAsync(context.Background(), dto.Config{})
+	Expect(err).To(BeNil())
+	Expect(res.SessionID).To(Equal("test-session"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	Expect(wait(ctx)).To(Succeed())
+	Expect(time.Since(started)).To(BeNumerically("<", time.Second))
+
+	// stream is still open, so waiting can be resumed and then detached with closing the stream
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	Expect(wait(ctx, WithCloseOnCancel())).To(Succeed())
+	Expect(wait(context.Background())).To(Succeed())
+}
+
+func TestRunAsyncErrorFrame(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"sessionID":"test-session"}` + "\n"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("\n" + `{"sessionID":"test-session","meta":{"error":"browser crashed","requestUID":"uid"}}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	_, wait, err := NewClient(server.URL, "key", time.Second, time.Minute).RunAsync(context.Background(), dto.Config{})
+	Expect(err).To(BeNil())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = wait(ctx)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal(`baas returned error: browser crashed, baas RequestUID: "uid"`))
+}
+
+func TestMessageRequestID(t *testing.T) {
+	RegisterTestingT(t)
+
+	var received dto.BrowserMessageIn
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+		_, _ = w.Write([]byte(`{"requestID":"other","value":"wrong"}` + "\n" + `{"requestID":"trace-123","value":"right"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", time.Second, time.Minute)
+	res, err := c.Message(context.Background(), dto.BrowserMessageIn{SessionID: "session", RequestID: "trace-123", Program: "getURL()"})
+	Expect(err).To(BeNil())
+	Expect(received.RequestID).To(Equal("trace-123"))
+	Expect(res.Value).To(Equal("right"))
+
+	_, err = c.Message(context.Background(), dto.BrowserMessageIn{SessionID: "session", RequestID: "trace-123", Program: "getURL()"})
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring(`request ID "trace-123" has already been used`))
+}
+
+func TestRequestIDsAreBounded(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"sessionID":"test-session"}` + "\n"))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", time.Second, time.Minute).(*baasClient)
+	for i := 0; i <= maxRequestIDsPerSession; i++ {
+		Expect(c.registerRequestID("test-session", fmt.Sprint(i))).To(Succeed())
+	}
+	// only the most recent IDs are remembered
+	Expect(c.registerRequestID("test-session", "0")).To(Succeed())
+	Expect(c.registerRequestID("test-session", fmt.Sprint(maxRequestIDsPerSession))).NotTo(Succeed())
+	Expect(c.requestIDs["test-session"].order).To(HaveLen(maxRequestIDsPerSession))
+
+	// IDs are dropped once the session ends
+	_, wait, err := c.RunAsync(context.Background(), dto.Config{})
+	Expect(err).To(BeNil())
+	Expect(wait(context.Background())).To(Succeed())
+	Eventually(func() int {
+		c.requestIDsMu.Lock()
+		defer c.requestIDsMu.Unlock()
+		return len(c.requestIDs)
+	}).Should(BeZero())
+}
+
+func TestMessageSlowBody(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"requestID":"slow","value":"done"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", 100*time.Millisecond, 5*time.Second)
+	res, err := c.Message(context.Background(), dto.BrowserMessageIn{SessionID: "session", RequestID: "slow"})
+	Expect(err).To(BeNil())
+	Expect(res.Value).To(Equal("done"))
+
+	_, err = c.Message(context.Background(), dto.BrowserMessageIn{SessionID: "session", RequestID: "slow-timeout", Timeout: "100ms"})
+	Expect(err).NotTo(BeNil())
+}
+
+func TestMessageLargeInteger(t *testing.T) {
+	RegisterTestingT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"requestID":"id","value":9007199254740993}`))
+	}))
+	defer server.Close()
+
+	res, err := NewClient(server.URL, "key", time.Second, time.Minute).Message(context.Background(), dto.BrowserMessageIn{SessionID: "session", RequestID: "id"})
+	Expect(err).To(BeNil())
+	Expect(res.Value).To(Equal(json.Number("9007199254740993")))
+
+	value, err := valueToInt(res.Value)
+	Expect(err).To(BeNil())
+	Expect(value).To(Equal(9007199254740993))
+}
+
+func TestRunAsyncSessionTags(t *testing.T) {
+	RegisterTestingT(t)
+
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+		_, _ = w.Write([]byte(`{"sessionID":"test-session"}` + "\n"))
+	}))
+	defer server.Close()
+
+	_, _, err := NewClient(server.URL, "key", time.Second, time.Minute).RunAsync(context.Background(), dto.Config{
+		SessionTags: map[string]string{"tenant": "acme", "job": "nightly"},
+	})
+	Expect(err).To(BeNil())
+	Expect(<-bodies).To(ContainSubstring(`"sessionTags":{"job":"nightly","tenant":"acme"}`))
+}
+
+func TestNewProgramMaxAttempts(t *testing.T) {
+	RegisterTestingT(t)
+
+	configs := make(chan dto.Config, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cfg dto.Config
+		_ = json.NewDecoder(r.Body).Decode(&cfg)
+		configs <- cfg
+		_, _ = w.Write([]byte(`{"sessionID":"test-session"}` + "\n"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := NewProgram(ctx, Config{Url: server.URL, MaxAttempts: lo.ToPtr(5)}, &testReporter{})
+	Expect(err).To(BeNil())
+	Expect((<-configs).MaxAttempts).To(Equal(lo.ToPtr(5)))
+
+	// backend default applies when not set
+	_, err = NewProgram(ctx, Config{Url: server.URL}, &testReporter{})
+	Expect(err).To(BeNil())
+	Expect((<-configs).MaxAttempts).To(BeNil())
+}
+
+func TestFetchScreenshot(t *testing.T) {
+	RegisterTestingT(t)
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if r.URL.Path != "/screenshots/home.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "key", time.Second, time.Minute)
+	data, err := c.FetchScreenshot(context.Background(), server.URL+"/screenshots/home.png")
+	Expect(err).To(BeNil())
+	Expect(data).To(Equal([]byte("png")))
+	Expect(authorization).To(Equal("Bearer key"))
+
+	_, err = c.FetchScreenshot(context.Background(), server.URL+"/screenshots/missing.png")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("status code 404"))
+
+	// API key is not leaked to other hosts (e.g. pre-signed storage URLs)
+	data, err = NewClient("https://baas.example.com", "key", time.Second, time.Minute).
+		FetchScreenshot(context.Background(), server.URL+"/screenshots/home.png")
+	Expect(err).To(BeNil())
+	Expect(data).To(Equal([]byte("png")))
+	Expect(authorization).To(BeEmpty())
+}
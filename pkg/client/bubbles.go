@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -32,26 +34,34 @@ type (
 var headerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF88")).Background(lipgloss.Color("#444444"))
 
 type CliClient struct {
-	viewport              viewport.Model
-	messages              []string
-	textarea              textarea.Model
-	senderStyle           lipgloss.Style
-	responseStyle         lipgloss.Style
-	errorStyle            lipgloss.Style
-	err                   error
-	baas                  Client
-	ctx                   context.Context
-	sessionID             string
-	sessionMeta           *service.ResultMeta
-	outDir                string
-	loader                spinner.Model
-	inProgress            atomic.Bool
-	programHistory        []string
-	programHistoryPointer int
-	cfg                   Config
+	viewport      viewport.Model
+	messages      []string
+	textarea      textarea.Model
+	senderStyle   lipgloss.Style
+	responseStyle lipgloss.Style
+	errorStyle    lipgloss.Style
+	err           error
+	baas          Client
+	ctx           context.Context
+	sessionID     string
+	sessionMeta   *service.ResultMeta
+	outDir        string
+	loader        spinner.Model
+	inProgress    atomic.Bool
+	history       History
+	transcriptMu  sync.Mutex // guards transcript recorded by both Update and async command handlers
+	transcript    []TranscriptEntry
+	cfg           Config
+	reporter      Reporter
 }
 
+// BubbleClient creates interactive TUI client
 func BubbleClient(ctx context.Context, cfg Config) (tea.Model, error) {
+	return BubbleClientWithReporter(ctx, cfg, nil)
+}
+
+// BubbleClientWithReporter creates interactive TUI client, reporter (optional) receives events of every command sent
+func BubbleClientWithReporter(ctx context.Context, cfg Config, reporter Reporter) (tea.Model, error) {
 	ta := textarea.New()
 	ta.Placeholder = "Start typing program... (or press Ctrl^C to exit, use Up and Down to navigate)"
 	ta.Focus()
@@ -73,7 +83,7 @@ func BubbleClient(ctx context.Context, cfg Config) (tea.Model, error) {
 	ta.KeyMap.InsertNewline.SetEnabled(false)
 
 	fmt.Printf("Connecting to %s...\n", cfg.Url)
-	baas := NewClient(cfg.Url, cfg.ApiKey, time.Second*30)
+	baas := NewClient(cfg.Url, cfg.ApiKey, DefaultConnectTimeout, DefaultResponseTimeout)
 	loader := spinner.New(
 		spinner.WithStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("205"))),
 		spinner.WithSpinner(spinner.Dot),
@@ -91,9 +101,16 @@ func BubbleClient(ctx context.Context, cfg Config) (tea.Model, error) {
 		loader:        loader,
 		err:           nil,
 		cfg:           cfg,
+		reporter:      reporter,
 	}
 
-	if outDir, err := os.MkdirTemp(os.TempDir(), "baas-response"); err == nil {
+	if cfg.OutDir != "" {
+		if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
+			cancel()
+			return nil, errors.Wrapf(err, "failed to init out dir")
+		}
+		c.outDir = cfg.OutDir
+	} else if outDir, err := os.MkdirTemp(os.TempDir(), "baas-response"); err == nil {
 		c.outDir = outDir
 	} else {
 		cancel()
@@ -105,29 +122,35 @@ func BubbleClient(ctx context.Context, cfg Config) (tea.Model, error) {
 		defer cancel()
 		defer c.updateMessages()
 		res, wait, err := baas.RunAsync(ctx, dto.Config{
-			Browser: dto.BrowserOpts{
-				Headful:          cfg.LocalDebug,
-				ReturnScreenshot: lo.ToPtr(true),
-				Timeout:          cfg.Timeout,
-			},
+			Browser:        cfg.startBrowserOpts(),
 			UseRandomProxy: lo.ToPtr(cfg.UseProxy),
+			MaxAttempts:    cfg.MaxAttempts,
+			SessionTags:    util.SliceToMap(cfg.Tags),
 		})
 		if err != nil {
 			c.messages = append(c.messages, c.errorStyle.Render("Browser: ")+"Failed to start session: "+err.Error())
+			c.record(TranscriptError, "Failed to start session: "+err.Error())
 			c.err = errors.Wrapf(err, "failed to start session")
 			return
 		}
 		if res.Error != "" {
 			c.err = errors.Errorf("%s", res.Error)
 			c.messages = append(c.messages, c.errorStyle.Render("Browser: ")+"Failed to start session: "+res.Error)
+			c.record(TranscriptError, "Failed to start session: "+res.Error)
 			return
 		}
 		c.sessionID = res.SessionID
 		c.messages = append(c.messages, c.responseStyle.Render("Browser: ")+fmt.Sprintf("Started session %s at %s", res.SessionID, cfg.Url))
+		c.record(TranscriptBrowser, fmt.Sprintf("Started session %s at %s", res.SessionID, cfg.Url))
 		c.updateMessages()
 		c.inProgress.Store(false)
-		wait()
+		if err := wait(ctx, WithCloseOnCancel()); err != nil {
+			c.err = err
+			c.messages = append(c.messages, c.errorStyle.Render("Browser: ")+"Session failed: "+err.Error())
+			c.record(TranscriptError, "Session failed: "+err.Error())
+		}
 		c.messages = append(c.messages, c.errorStyle.Render("Browser: ")+fmt.Sprintf("Session %s has been terminated", res.SessionID))
+		c.record(TranscriptBrowser, fmt.Sprintf("Session %s has been terminated", res.SessionID))
 		c.updateMessages()
 	}()
 	c.displaySpinner()
@@ -160,18 +183,15 @@ func (m *CliClient) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.Type {
 		case tea.KeyCtrlC, tea.KeyEsc:
 			fmt.Println(m.textarea.Value())
+			m.saveTranscript()
 			return m, tea.Quit
 		case tea.KeyUp:
-			if m.programHistoryPointer < len(m.programHistory) {
-				m.programHistoryPointer++
-				m.textarea.SetValue(m.programHistory[len(m.programHistory)-m.programHistoryPointer])
+			if value, ok := m.history.Prev(m.textarea.Value()); ok {
+				m.textarea.SetValue(value)
 			}
 		case tea.KeyDown:
-			if m.programHistoryPointer > 0 {
-				m.programHistoryPointer--
-				m.textarea.SetValue(m.programHistory[len(m.programHistory)-m.programHistoryPointer-1])
-			} else {
-				m.textarea.SetValue("")
+			if value, ok := m.history.Next(m.textarea.Value()); ok {
+				m.textarea.SetValue(value)
 			}
 		case tea.KeyEnter:
 			m.inProgress.Store(true)
@@ -179,6 +199,11 @@ func (m *CliClient) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.loader.Tick()
 			go func() {
 				defer m.inProgress.Store(false)
+				if m.sessionID == "" {
+					m.processResponse(nil, ErrNoActiveSession)
+					return
+				}
+				started := time.Now()
 				res, err := m.baas.Message(m.ctx, dto.BrowserMessageIn{
 					SessionID: m.sessionID,
 					Program:   currentValue,
@@ -186,12 +211,15 @@ func (m *CliClient) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					Values:    util.SliceToMap(m.cfg.Values),
 					Secrets:   util.SliceToMap(m.cfg.Secrets),
 				})
+				if m.reporter != nil {
+					reportCommandEvent(m.reporter, currentValue, started, res, err)
+				}
 				m.processResponse(res, err)
 			}()
 			m.displaySpinner()
-			m.programHistory = append(m.programHistory, currentValue)
-			m.programHistoryPointer = 0
+			m.history.Add(currentValue)
 			m.messages = append(m.messages, m.senderStyle.Render("You: ")+currentValue)
+			m.record(TranscriptYou, currentValue)
 			m.updateMessages()
 		}
 
@@ -227,15 +255,18 @@ func (m *CliClient) processResponse(res *dto.BrowserMessageOut, err error) {
 	if err != nil {
 		m.err = err
 		m.messages = append(m.messages, m.errorStyle.Render("ERROR: "+err.Error()))
+		m.record(TranscriptError, err.Error())
 		return
 	}
 	if res.Error != "" {
 		m.err = errors.Errorf("%s", res.Error)
 		m.messages = append(m.messages, m.errorStyle.Render("ERROR: "+res.Error))
+		m.record(TranscriptError, res.Error)
 		return
 	}
 	m.sessionMeta = lo.ToPtr(res.Meta)
 	m.messages = append(m.messages, m.responseStyle.Render("Browser: ")+fmt.Sprintf("%v", res.Value))
+	m.record(TranscriptBrowser, fmt.Sprintf("%v", res.Value))
 	if len(res.Screenshots) > 0 {
 		for name, screenshot := range res.Screenshots {
 			m.saveFile(m.outDir, "screenshot", name, screenshot)
@@ -246,6 +277,30 @@ func (m *CliClient) processResponse(res *dto.BrowserMessageOut, err error) {
 	}
 }
 
+func (m *CliClient) record(role, text string) {
+	m.transcriptMu.Lock()
+	defer m.transcriptMu.Unlock()
+	m.transcript = append(m.transcript, TranscriptEntry{Timestamp: time.Now(), Role: role, Text: text})
+}
+
+func (m *CliClient) saveTranscript() {
+	fileName := filepath.Join(m.outDir, fmt.Sprintf("transcript-%s.ndjson", time.Now().Format("20060102-150405")))
+	file, err := os.Create(fileName)
+	if err != nil {
+		fmt.Printf("failed to save transcript to %s: %q\n", fileName, err.Error())
+		return
+	}
+	defer file.Close()
+	m.transcriptMu.Lock()
+	transcript := slices.Clone(m.transcript)
+	m.transcriptMu.Unlock()
+	if err := WriteTranscript(file, transcript, util.SliceToMap(m.cfg.Secrets)); err != nil {
+		fmt.Printf("failed to save transcript to %s: %q\n", fileName, err.Error())
+		return
+	}
+	fmt.Println("transcript saved to " + termlink.ColorLink(fileName, fmt.Sprintf("file://%s", fileName), "italic green"))
+}
+
 func (m *CliClient) saveFile(outDir string, fileType, name string, screenshot []byte) {
 	fileName := filepath.Join(outDir, fmt.Sprintf("%s.png", name))
 	if fileType != "screenshot" {
@@ -254,9 +309,11 @@ func (m *CliClient) saveFile(outDir string, fileType, name string, screenshot []
 	var message string
 	if err := os.WriteFile(fileName, screenshot, 0o644); err != nil {
 		message = fmt.Sprintf("failed to save %s %q to %s: %q", fileType, name, fileName, err.Error())
+		m.record(TranscriptBrowser, message)
 	} else {
 		message = fmt.Sprintf("%s %q saved to ", fileType, name) +
 			termlink.ColorLink(name, fmt.Sprintf("file://%s", fileName), "italic green")
+		m.record(TranscriptBrowser, fmt.Sprintf("%s %q saved to %s", fileType, name, fileName))
 	}
 	m.messages = append(m.messages, m.responseStyle.Render("Browser: ")+message)
 }
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
 )
 
 type testReporter struct{}
@@ -36,3 +38,72 @@ func newLocalDebugProgram(t *testing.T, opts ...Option) (Program, context.Cancel
 
 	return p, cancel
 }
+
+type mockClient struct {
+	programs    []string
+	respond     func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error)
+	screenshots map[string][]byte // screenshots delivered by URL
+	fetched     []string
+	started     []dto.Config // configs of started sessions
+}
+
+func (c *mockClient) RunAsync(ctx context.Context, baasRequest dto.Config) (*dto.BrowserMessageOut, WaitFunc, error) {
+	c.started = append(c.started, baasRequest)
+	// newMockProgram starts with test-session, so sessions started later are numbered
+	return &dto.BrowserMessageOut{SessionID: fmt.Sprintf("test-session-%d", len(c.started))}, func(ctx context.Context, opts ...WaitOption) error { return nil }, nil
+}
+
+func (c *mockClient) Message(ctx context.Context, msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+	c.programs = append(c.programs, msg.Program)
+	if c.respond != nil {
+		return c.respond(msg)
+	}
+	return &dto.BrowserMessageOut{SessionID: msg.SessionID, RequestID: msg.RequestID}, nil
+}
+
+func newMockProgram(t *testing.T, respond func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error), opts ...Option) (*program, *mockClient) {
+	RegisterTestingT(t)
+
+	c := &mockClient{respond: respond}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	p := &program{
+		client:    c,
+		ctx:       ctx,
+		cancel:    cancel,
+		sessionID: "test-session",
+		reporter:  &testReporter{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, c
+}
+
+func (c *mockClient) FetchScreenshot(ctx context.Context, url string) ([]byte, error) {
+	c.fetched = append(c.fetched, url)
+	screenshot, found := c.screenshots[url]
+	if !found {
+		return nil, fmt.Errorf("screenshot %s not found", url)
+	}
+	return screenshot, nil
+}
+
+// startedConfig starts program against mockClient and returns the config of the started session
+func startedConfig(t *testing.T, cfg Config, opts ...Option) dto.Config {
+	RegisterTestingT(t)
+
+	c := &mockClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	_, err := newProgram(ctx, c, cfg, &testReporter{}, opts...)
+	Expect(err).To(BeNil())
+	Expect(c.started).To(HaveLen(1))
+	return c.started[0]
+}
+
+func valueResponse(value any) func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+	return func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{SessionID: msg.SessionID, RequestID: msg.RequestID, Value: value}, nil
+	}
+}
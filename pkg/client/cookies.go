@@ -0,0 +1,51 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func (p *program) snapshotCookies(command string) {
+	res, err := p.sendProgram(p.functionCall0("getCookies"))
+	if err != nil {
+		p.report(ReportLevelError, fmt.Sprintf("Failed to snapshot cookies: %v", err))
+		return
+	}
+	var cookies []dto.BrowserCookie
+	if err := decodeValue(res.Value, &cookies); err != nil {
+		p.report(ReportLevelError, fmt.Sprintf("Failed to snapshot cookies: %v", err))
+		return
+	}
+	p.cookieChanges = append(p.cookieChanges, diffCookies(command, p.cookies, cookies)...)
+	p.cookies = cookies
+	p.lastCookies = cookies
+}
+
+func cookieKey(cookie dto.BrowserCookie) string {
+	return cookie.Domain + cookie.Path + ";" + cookie.Name
+}
+
+func diffCookies(command string, before, after []dto.BrowserCookie) []dto.CookieDelta {
+	var res []dto.CookieDelta
+	beforeByKey := make(map[string]dto.BrowserCookie, len(before))
+	for _, cookie := range before {
+		beforeByKey[cookieKey(cookie)] = cookie
+	}
+	afterKeys := make(map[string]bool, len(after))
+	for _, cookie := range after {
+		afterKeys[cookieKey(cookie)] = true
+		prev, found := beforeByKey[cookieKey(cookie)]
+		if !found {
+			res = append(res, dto.CookieDelta{Command: command, Change: dto.CookieAdded, Cookie: cookie})
+		} else if prev != cookie {
+			res = append(res, dto.CookieDelta{Command: command, Change: dto.CookieChanged, Cookie: cookie})
+		}
+	}
+	for _, cookie := range before {
+		if !afterKeys[cookieKey(cookie)] {
+			res = append(res, dto.CookieDelta{Command: command, Change: dto.CookieRemoved, Cookie: cookie})
+		}
+	}
+	return res
+}
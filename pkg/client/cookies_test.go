@@ -0,0 +1,63 @@
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func TestCookieChanges(t *testing.T) {
+	session := map[string]any{"name": "session", "value": "1", "domain": "example.com", "path": "/"}
+	csrf := map[string]any{"name": "csrf", "value": "a", "domain": "example.com", "path": "/"}
+	csrfRotated := map[string]any{"name": "csrf", "value": "b", "domain": "example.com", "path": "/"}
+	snapshots := [][]any{{csrf}, {session, csrfRotated}, {csrfRotated}}
+
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		res := &dto.BrowserMessageOut{}
+		if msg.Program == "getCookies()" {
+			res.Value, snapshots = snapshots[0], snapshots[1:]
+		}
+		return res, nil
+	}, WithCookieTracking())
+
+	Expect(p.Navigate("https://example.com")).To(Succeed())
+	Expect(p.Click("#login")).To(Succeed())
+	Expect(p.Click("#logout")).To(Succeed())
+
+	cookie := func(name, value string) dto.BrowserCookie {
+		return dto.BrowserCookie{Name: name, Value: value, Domain: "example.com", Path: "/"}
+	}
+	Expect(p.CookieChanges()).To(Equal([]dto.CookieDelta{
+		{Command: "navigate('https://example.com')", Change: dto.CookieAdded, Cookie: cookie("csrf", "a")},
+		{Command: "click('#login')", Change: dto.CookieAdded, Cookie: cookie("session", "1")},
+		{Command: "click('#login')", Change: dto.CookieChanged, Cookie: cookie("csrf", "b")},
+		{Command: "click('#logout')", Change: dto.CookieRemoved, Cookie: cookie("session", "1")},
+	}))
+	Expect(c.programs).To(HaveLen(6))
+}
+
+func TestSetCookies(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.SetCookies([]dto.BrowserCookie{
+		{Name: "session", Value: "a'b\"c", Domain: "example.com", Path: "/", HTTPOnly: true, Secure: true},
+		{Name: "theme", Value: "dark", Domain: "example.com", Path: "/"},
+	})).To(Succeed())
+	Expect(c.programs).To(Equal([]string{
+		`setCookies([{"name":"session","value":"a'b\"c","domain":"example.com","path":"/","httpOnly":true,"secure":true},` +
+			`{"name":"theme","value":"dark","domain":"example.com","path":"/","httpOnly":false,"secure":false}])`,
+	}))
+}
+
+func TestWaitForCookie(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{
+		"name": "sid", "value": "abc123", "domain": ".example.com", "path": "/", "httpOnly": true, "secure": true,
+	}))
+
+	cookie, err := p.WaitForCookie("sid", "30s", WithCookieDomain("example.com"))
+	Expect(err).To(BeNil())
+	Expect(cookie).To(Equal(dto.BrowserCookie{Name: "sid", Value: "abc123", Domain: ".example.com", Path: "/", HTTPOnly: true, Secure: true}))
+	Expect(c.programs).To(Equal([]string{"waitForCookie('sid', '30s', 'domain:example.com')"}))
+}
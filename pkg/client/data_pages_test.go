@@ -0,0 +1,82 @@
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDataPageScrollElement(t *testing.T) {
+	p, cancel := newLocalDebugProgram(t)
+	defer cancel()
+
+	err := p.Navigate(`data:text/html,<div id="log" style="height:50px;overflow:auto"><div style="height:500px"></div></div>`)
+	Expect(err).To(BeNil())
+
+	err = p.ScrollElement("#log", 0, 100)
+	Expect(err).To(BeNil())
+
+	err = p.Assert("document.querySelector('#log').scrollTop === 100")
+	Expect(err).To(BeNil())
+}
+
+func TestDataPageSourceVsRendered(t *testing.T) {
+	p, cancel := newLocalDebugProgram(t)
+	defer cancel()
+
+	err := p.Navigate(`data:text/html,<div id="app"></div><script>document.getElementById("app").textContent="rendered"</script>`)
+	Expect(err).To(BeNil())
+
+	source, err := p.GetPageSource()
+	Expect(err).To(BeNil())
+	Expect(source).To(ContainSubstring(`<div id="app"></div>`))
+
+	rendered, err := p.OuterHtml("#app")
+	Expect(err).To(BeNil())
+	Expect(rendered).To(Equal(`<div id="app">rendered</div>`))
+}
+
+func TestDataPageWaitAndGetText(t *testing.T) {
+	p, cancel := newLocalDebugProgram(t)
+	defer cancel()
+
+	err := p.Navigate(`data:text/html,<div id="root"></div><script>setTimeout(()=>{document.getElementById("root").innerHTML="<div class=banner>Saved</div>"},500)</script>`, WithEncodeURL())
+	Expect(err).To(BeNil())
+
+	text, err := p.WaitAndGetText(".banner", WithTimeout("5s"))
+	Expect(err).To(BeNil())
+	Expect(text).To(Equal("Saved"))
+}
+
+func TestDataPageGetImages(t *testing.T) {
+	p, cancel := newLocalDebugProgram(t)
+	defer cancel()
+
+	err := p.Navigate(`data:text/html,<img alt="first" src="data:image/gif;base64,R0lGODlhAQABAAAAACw="><div id="gallery"><img alt="second"><img alt="third"></div>`, WithEncodeURL())
+	Expect(err).To(BeNil())
+
+	images, err := p.GetImages()
+	Expect(err).To(BeNil())
+	Expect(images).To(HaveLen(3))
+
+	images, err = p.GetImages(WithSelector("#gallery"))
+	Expect(err).To(BeNil())
+	Expect(images).To(HaveLen(2))
+}
+
+func TestDataPageClickInShadowRoot(t *testing.T) {
+	p, cancel := newLocalDebugProgram(t)
+	defer cancel()
+
+	err := p.Navigate(`data:text/html,<div id="host"></div><script>const b=document.createElement("button");b.id="inner";b.textContent="Go";b.onclick=()=>{document.body.dataset.clicked="yes"};document.getElementById("host").attachShadow({mode:"open"}).appendChild(b)</script>`, WithEncodeURL())
+	Expect(err).To(BeNil())
+
+	err = p.WaitVisible("#inner", WithPierceShadow(), WithTimeout("5s"))
+	Expect(err).To(BeNil())
+
+	err = p.Click("#inner", WithPierceShadow())
+	Expect(err).To(BeNil())
+
+	err = p.Assert("document.body.dataset.clicked === 'yes'")
+	Expect(err).To(BeNil())
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+// Downloads returns all files downloaded within the session so far
+func (p *program) Downloads() []dto.DownloadedFile {
+	return p.downloads
+}
+
+// SaveAllDownloads writes all files downloaded within the session to dir,
+// files with the same names are disambiguated with numeric suffixes (e.g. report-1.csv)
+func (p *program) SaveAllDownloads(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	used := map[string]bool{}
+	for _, file := range p.downloads {
+		fileName := filepath.Join(dir, uniqueFileName(filepath.Base(file.Name), used))
+		if err := os.WriteFile(fileName, file.Data, 0o644); err != nil {
+			return errors.Wrapf(err, "failed to save file %s", fileName)
+		}
+		p.report(ReportLevelInfo, fmt.Sprintf("%q saved to %s", file.Name, fileName))
+	}
+	return nil
+}
+
+func uniqueFileName(name string, used map[string]bool) string {
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "download"
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	res := name
+	for i := 1; used[res]; i++ {
+		res = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	used[res] = true
+	return res
+}
+
+const (
+	DownloadPDF = "pdf"
+	DownloadZip = "zip"
+	DownloadCSV = "csv"
+)
+
+// ValidateDownload checks that data is a valid file of the given kind (DownloadPDF, DownloadZip or DownloadCSV),
+// e.g. to detect an HTML error page returned instead of the expected file
+func ValidateDownload(kind string, data []byte) error {
+	if len(data) == 0 {
+		return errors.Errorf("downloaded file is empty")
+	}
+	switch kind {
+	case DownloadPDF:
+		if !bytes.HasPrefix(data, []byte("%PDF-")) {
+			return errors.Errorf("downloaded file is not a PDF: missing %%PDF- header")
+		}
+		if !bytes.Contains(data[max(0, len(data)-1024):], []byte("%%EOF")) {
+			return errors.Errorf("downloaded PDF is truncated: missing %%%%EOF marker")
+		}
+	case DownloadZip:
+		if _, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+			return errors.Wrapf(err, "downloaded file is not a valid zip archive")
+		}
+	case DownloadCSV:
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+			return errors.Errorf("downloaded file is not a CSV: looks like HTML/XML")
+		}
+		if _, err := csv.NewReader(bytes.NewReader(data)).ReadAll(); err != nil {
+			return errors.Wrapf(err, "downloaded file is not a valid CSV")
+		}
+	default:
+		return errors.Errorf("unsupported download kind %q", kind)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func TestSaveAllDownloads(t *testing.T) {
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{DownloadedFileName: "report.csv", DownloadedFile: []byte(msg.Program)}, nil
+	})
+
+	Expect(p.Click("#export-january")).To(Succeed())
+	Expect(p.Click("#export-february")).To(Succeed())
+	Expect(p.Downloads()).To(Equal([]dto.DownloadedFile{
+		{Name: "report.csv", Data: []byte("click('#export-january')")},
+		{Name: "report.csv", Data: []byte("click('#export-february')")},
+	}))
+
+	dir := t.TempDir()
+	Expect(p.SaveAllDownloads(dir)).To(Succeed())
+
+	data, err := os.ReadFile(filepath.Join(dir, "report.csv"))
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(Equal("click('#export-january')"))
+	data, err = os.ReadFile(filepath.Join(dir, "report-1.csv"))
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(Equal("click('#export-february')"))
+}
+
+func TestValidateDownload(t *testing.T) {
+	RegisterTestingT(t)
+
+	pdf := []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\ntrailer\n<<>>\n%%EOF\n")
+	Expect(ValidateDownload(DownloadPDF, pdf)).To(Succeed())
+	Expect(ValidateDownload(DownloadPDF, pdf[:20])).NotTo(Succeed())
+	htmlErrorPage := []byte("<!DOCTYPE html><html><body>Session expired</body></html>")
+	Expect(ValidateDownload(DownloadPDF, htmlErrorPage)).NotTo(Succeed())
+
+	var zipBuf bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuf)
+	_, err := zipWriter.Create("report.csv")
+	Expect(err).To(BeNil())
+	Expect(zipWriter.Close()).To(Succeed())
+	Expect(ValidateDownload(DownloadZip, zipBuf.Bytes())).To(Succeed())
+	Expect(ValidateDownload(DownloadZip, htmlErrorPage)).NotTo(Succeed())
+
+	Expect(ValidateDownload(DownloadCSV, []byte("name,value\n\"a, b\",1\n"))).To(Succeed())
+	Expect(ValidateDownload(DownloadCSV, []byte("name,value\n\"unterminated,1\n"))).NotTo(Succeed())
+	Expect(ValidateDownload(DownloadCSV, htmlErrorPage)).NotTo(Succeed())
+
+	Expect(ValidateDownload("docx", pdf)).NotTo(Succeed())
+	Expect(ValidateDownload(DownloadPDF, nil)).NotTo(Succeed())
+}
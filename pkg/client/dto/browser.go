@@ -7,10 +7,11 @@ import (
 )
 
 type Config struct {
-	Browser        BrowserOpts `json:"browser" yaml:"browser"`
-	SessionID      *string     `json:"sessionID" yaml:"sessionID"`                               // sessionID to use when running async requests (must be unique)
-	MaxAttempts    *int        `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`       // max amount of attempts to fetch/process (default: 3)
-	UseRandomProxy *bool       `json:"useRandomProxy,omitempty" yaml:"useRandomProxy,omitempty"` // whether to use random proxy from the configured proxy pool (default: false)
+	Browser        BrowserOpts       `json:"browser" yaml:"browser"`
+	SessionID      *string           `json:"sessionID" yaml:"sessionID"`                               // sessionID to use when running async requests (must be unique)
+	MaxAttempts    *int              `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`       // max amount of attempts to fetch/process (default: 3)
+	UseRandomProxy *bool             `json:"useRandomProxy,omitempty" yaml:"useRandomProxy,omitempty"` // whether to use random proxy from the configured proxy pool (default: false)
+	SessionTags    map[string]string `json:"sessionTags,omitempty" yaml:"sessionTags,omitempty"`       // tags to label session with in the backend (e.g. job name, tenant)
 }
 
 type Result struct {
@@ -20,18 +21,77 @@ type Result struct {
 }
 
 type BrowserOpts struct {
-	Headful             bool              `json:"headful" default:"false"`                              // run headful chrome (default: false)
-	ReturnScreenshot    *bool             `json:"returnScreenshot" default:"false"`                     // whether to return screenshot after execution
-	UserAgent           string            `json:"userAgent" default:""`                                 // use user-agent (default: undefined)
-	UseProxy            *string           `json:"useProxy" default:""`                                  // use specific proxy server (default: undefined)
-	Cookies             []BrowserCookie   `json:"cookies"`                                              // cookies to set before executing actions
-	Program             string            `json:"program" required:"true"`                              // program to run (required)
-	Secrets             map[string]string `json:"secrets" required:"false"`                             // program secrets to use (values can be obtained as getSecret('name'))
-	Values              map[string]string `json:"values" required:"false"`                              // program values to use (values can be obtained as getValue('name'))
-	WaitForFileDownload *bool             `json:"waitForFileDownload" required:"false" default:"false"` // whether to wait until file is downloaded
-	Timeout             string            `json:"timeout" example:"60s" default:"60s"`                  // duration string in go duration format (e.g.: 10s)
-	Width               *int              `json:"width" example:"1920" default:"1920"`                  // width of the browser window
-	Height              *int              `json:"height" example:"1080" default:"1080"`                 // height of the browser window
+	Headful             bool               `json:"headful" default:"false"`                              // run headful chrome (default: false)
+	ReturnScreenshot    *bool              `json:"returnScreenshot" default:"false"`                     // whether to return screenshot after execution
+	UserAgent           string             `json:"userAgent" default:""`                                 // use user-agent (default: undefined)
+	UseProxy            *string            `json:"useProxy" default:""`                                  // use specific proxy server (default: undefined)
+	Cookies             []BrowserCookie    `json:"cookies"`                                              // cookies to set before executing actions
+	Program             string             `json:"program" required:"true"`                              // program to run (required)
+	Secrets             map[string]string  `json:"secrets" required:"false"`                             // program secrets to use (values can be obtained as getSecret('name'))
+	Values              map[string]string  `json:"values" required:"false"`                              // program values to use (values can be obtained as getValue('name'))
+	WaitForFileDownload *bool              `json:"waitForFileDownload" required:"false" default:"false"` // whether to wait until file is downloaded
+	Timeout             string             `json:"timeout" example:"60s" default:"60s"`                  // duration string in go duration format (e.g.: 10s)
+	Width               *int               `json:"width" example:"1920" default:"1920"`                  // width of the browser window
+	Height              *int               `json:"height" example:"1080" default:"1080"`                 // height of the browser window
+	NetworkThrottle     *NetworkConditions `json:"networkThrottle" required:"false"`                     // network conditions to emulate from the session start
+	InitScripts         []string           `json:"initScripts" required:"false"`                         // scripts to run before page scripts on every navigation for the session's lifetime
+	Stealth             *bool              `json:"stealth" required:"false" default:"false"`             // whether to enable common anti-detection evasions (default: false)
+	StealthOptions      *StealthOptions    `json:"stealthOptions" required:"false"`                      // granular control over evasions enabled by Stealth
+	ColorScheme         string             `json:"colorScheme" required:"false" example:"dark"`          // emulated prefers-color-scheme: dark, light or no-preference (default: undefined)
+	ReducedMotion       *bool              `json:"reducedMotion" required:"false" default:"false"`       // whether to emulate prefers-reduced-motion: reduce
+	ScreenshotDelivery  string             `json:"screenshotDelivery" required:"false" default:"inline"` // how screenshots are returned: inline (bytes) or url (short-lived URL to fetch)
+	Locale              string             `json:"locale" required:"false" example:"en-US"`              // browser locale (navigator.language, Intl) (default: undefined)
+	Languages           []string           `json:"languages" required:"false"`                           // preferred languages (Accept-Language, navigator.languages) (default: [Locale])
+	CaptureResponses    []string           `json:"captureResponses" required:"false"`                    // URL patterns (regular expressions) of network responses to capture from the session start
+	GrantPermissions    []string           `json:"grantPermissions" required:"false"`                    // permissions granted to every origin from the session start (e.g. geolocation, notifications)
+	RecordWebSockets    *bool              `json:"recordWebSockets" required:"false" default:"false"`    // whether to record WebSocket frames for GetWebSocketMessages
+	Extra               map[string]any     `json:"-"`                                                    // arbitrary backend options merged into the payload (typed fields win on collision)
+}
+
+func (o BrowserOpts) MarshalJSON() ([]byte, error) {
+	type browserOpts BrowserOpts
+	return marshalWithExtra(browserOpts(o), o.Extra)
+}
+
+const (
+	ScreenshotDeliveryInline = "inline"
+	ScreenshotDeliveryURL    = "url"
+)
+
+const (
+	ColorSchemeDark         = "dark"
+	ColorSchemeLight        = "light"
+	ColorSchemeNoPreference = "no-preference"
+)
+
+const (
+	PermissionGranted = "granted"
+	PermissionDenied  = "denied"
+	PermissionPrompt  = "prompt"
+)
+
+type NetworkConditions struct {
+	Offline      bool `json:"offline"`      // whether network is disconnected
+	DownloadKbps int  `json:"downloadKbps"` // max download throughput in kbps
+	UploadKbps   int  `json:"uploadKbps"`   // max upload throughput in kbps
+	LatencyMs    int  `json:"latencyMs"`    // min latency added to each request in ms
+}
+
+// StealthOptions controls particular evasions applied when stealth mode is enabled (all are enabled when unset).
+// Evasions reduce the chance of being fingerprinted as a headless browser, but don't guarantee it:
+// they may break sites relying on the patched APIs and don't help against behavioral or IP-based detection
+type StealthOptions struct {
+	Webdriver   *bool `json:"webdriver,omitempty"`   // hide navigator.webdriver flag
+	Plugins     *bool `json:"plugins,omitempty"`     // emulate navigator.plugins of a regular browser
+	Languages   *bool `json:"languages,omitempty"`   // emulate navigator.languages of a regular browser
+	WebGLVendor *bool `json:"webglVendor,omitempty"` // report regular WebGL vendor and renderer
+}
+
+// Slow3G is the network conditions preset matching "Slow 3G" of Chrome DevTools
+var Slow3G = NetworkConditions{
+	DownloadKbps: 400,
+	UploadKbps:   400,
+	LatencyMs:    2000,
 }
 
 type BrowserMessageIn struct {
@@ -44,6 +104,12 @@ type BrowserMessageIn struct {
 	OperationTimeout        *string           `json:"operationTimeout" example:"20s" default:"20s"`            // timeout to execute a single command (default: 20s)
 	StopSession             *bool             `json:"stopSession" example:"true" default:"false"`              // tells browser to stop session
 	ErrorOnOperationTimeout *bool             `json:"errorOnOperationTimeout" required:"false" default:"true"` // whether to return error when a single operation times out (default: true)
+	Extra                   map[string]any    `json:"-"`                                                       // arbitrary backend options merged into the payload (typed fields win on collision)
+}
+
+func (i BrowserMessageIn) MarshalJSON() ([]byte, error) {
+	type browserMessageIn BrowserMessageIn
+	return marshalWithExtra(browserMessageIn(i), i.Extra)
 }
 
 func (i *BrowserMessageIn) Sanitized() any {
@@ -51,6 +117,7 @@ func (i *BrowserMessageIn) Sanitized() any {
 	inCopy := BrowserMessageIn{}
 	_ = json.Unmarshal(bytesIn, &inCopy)
 	inCopy.Secrets = nil
+	inCopy.Extra = i.Extra
 	return inCopy
 }
 
@@ -59,12 +126,16 @@ type BrowserMessageOut struct {
 	SessionID          string             `json:"sessionID" required:"true"`    // sessionID to send event to
 	RequestID          string             `json:"requestID"`                    // ID of the current request (used for internal purposes)
 	Meta               service.ResultMeta `json:"meta" yaml:"meta"`             // metadata related to processing
+	UsedProxy          string             `json:"usedProxy,omitempty"`          // which proxy server is used by the session
 	Error              string             `json:"error,omitempty" yaml:"error"` // error happened when running program
 	Value              any                `json:"value,omitempty" yaml:"value"` // return value
+	Reasoning          string             `json:"reasoning,omitempty"`          // reasoning of the LLM (returned by LLM actions)
 	Screenshots        map[string][]byte  `json:"screenshots,omitempty"`
+	ScreenshotURLs     map[string]string  `json:"screenshotURLs,omitempty"` // short-lived URLs of screenshots (if delivered by URL)
 	Log                []string           `json:"log,omitempty"`
 	DownloadedFile     []byte             `json:"downloadedFile,omitempty"`
 	DownloadedFileName string             `json:"downloadedFileName,omitempty"`
+	Responses          []NetworkResponse  `json:"responses,omitempty"` // network responses captured while running the program
 	OutHTML            string             `json:"outHtml"`
 }
 
@@ -77,6 +148,47 @@ type BrowserCookie struct {
 	Secure   bool   `json:"secure"`
 }
 
+const (
+	CookieAdded   = "added"
+	CookieChanged = "changed"
+	CookieRemoved = "removed"
+)
+
+type CookieDelta struct {
+	Command string        `json:"command"` // command after which the change was detected
+	Change  string        `json:"change"`  // type of the change: added, changed or removed
+	Cookie  BrowserCookie `json:"cookie"`  // cookie after the change (or before the change if it was removed)
+}
+
+type RedirectHop struct {
+	URL    string `json:"url"`    // requested URL
+	Status int    `json:"status"` // status code of the response
+}
+
+type NavigationResult struct {
+	URL           string        `json:"url"`           // final URL after following redirects
+	Status        int           `json:"status"`        // status code of the final response
+	RedirectChain []RedirectHop `json:"redirectChain"` // redirects followed before reaching the final URL
+}
+
+type URLResult struct {
+	URL   string `json:"url"`             // URL the program was run on
+	Value any    `json:"value,omitempty"` // value returned by the program
+	Error string `json:"error,omitempty"` // error happened when navigating or running the program
+}
+
+type NetworkResponse struct {
+	URL         string `json:"url"`         // URL of the request
+	Status      int    `json:"status"`      // HTTP status code of the response
+	ContentType string `json:"contentType"` // Content-Type header of the response
+	Body        []byte `json:"body"`        // raw body of the response
+}
+
+type DownloadedFile struct {
+	Name string `json:"name"` // name of the downloaded file
+	Data []byte `json:"data"` // contents of the downloaded file
+}
+
 type BrowserResponse struct {
 	OutHTML        string            `json:"outHtml"`
 	Screenshot     []byte            `json:"screenshot,omitempty"`
@@ -87,3 +199,111 @@ type BrowserResponse struct {
 	URL            string            `json:"url"`
 	Error          *string           `json:"error"`
 }
+
+type SessionInfo struct {
+	SessionID    string  `json:"sessionID" yaml:"sessionID"`                     // ID of the session
+	URL          string  `json:"url" yaml:"url"`                                 // current URL of the page
+	Title        string  `json:"title" yaml:"title"`                             // current title of the page
+	Cost         float64 `json:"cost" yaml:"cost"`                               // cost accumulated by all commands of the session
+	CommandCount int     `json:"commandCount" yaml:"commandCount"`               // amount of commands executed within the session
+	UsedProxy    string  `json:"usedProxy,omitempty" yaml:"usedProxy,omitempty"` // which proxy server is used by the session
+}
+
+type Viewport struct {
+	Width  int `json:"width"`  // width of the viewport in CSS pixels
+	Height int `json:"height"` // height of the viewport in CSS pixels
+}
+
+type SessionCapabilities struct {
+	BrowserVersion string   `json:"browserVersion"` // version of the browser (e.g. Chrome/124.0.6367.60)
+	UserAgent      string   `json:"userAgent"`      // user agent used by the session
+	Viewport       Viewport `json:"viewport"`       // current viewport size
+	Proxy          bool     `json:"proxy"`          // whether the session uses proxy
+	Recording      bool     `json:"recording"`      // whether the session is being recorded
+}
+
+type PageMeta struct {
+	Charset      string `json:"charset"`      // declared character encoding of the document (e.g. UTF-8)
+	Language     string `json:"language"`     // lang attribute of the document element (e.g. en-US)
+	Title        string `json:"title"`        // title of the document
+	CanonicalURL string `json:"canonicalURL"` // URL from <link rel="canonical"> (empty if not declared)
+}
+
+const (
+	WSDirectionSent     = "sent"
+	WSDirectionReceived = "received"
+)
+
+// WSMessage is a WebSocket frame recorded when BrowserOpts.RecordWebSockets is enabled,
+// payloads are capped by the backend (longer ones are cut and marked as truncated), binary frames are base64-encoded
+type WSMessage struct {
+	URL       string `json:"url"`       // URL of the WebSocket connection
+	Direction string `json:"direction"` // WSDirectionSent or WSDirectionReceived
+	Payload   string `json:"payload"`   // text of the frame (base64 for binary frames)
+	Binary    bool   `json:"binary"`    // whether the frame is binary
+	Truncated bool   `json:"truncated"` // whether the payload was cut by the backend size limit
+}
+
+type NetworkStats struct {
+	Requests      int                          `json:"requests"`      // number of requests made since the last navigation
+	BytesReceived int64                        `json:"bytesReceived"` // bytes received since the last navigation (including headers)
+	BytesSent     int64                        `json:"bytesSent"`     // bytes sent since the last navigation (including headers)
+	ByType        map[string]ResourceTypeStats `json:"byType"`        // breakdown by resource type (e.g. document, script, image, xhr)
+}
+
+type ResourceTypeStats struct {
+	Requests      int   `json:"requests"`      // number of requests of the type
+	BytesReceived int64 `json:"bytesReceived"` // bytes received for requests of the type
+	BytesSent     int64 `json:"bytesSent"`     // bytes sent for requests of the type
+}
+
+type ElementMetrics struct {
+	TextLength   int     `json:"textLength"`   // length of the visible text of the element
+	VisibleRatio float64 `json:"visibleRatio"` // fraction of the element's area within the viewport (0..1)
+	InViewport   bool    `json:"inViewport"`   // whether any part of the element is within the viewport
+}
+
+type ImageInfo struct {
+	Src           string `json:"src"`           // resolved source URL of the image
+	Alt           string `json:"alt"`           // alt text of the image
+	NaturalWidth  int    `json:"naturalWidth"`  // intrinsic width of the image in pixels (0 if not loaded)
+	NaturalHeight int    `json:"naturalHeight"` // intrinsic height of the image in pixels (0 if not loaded)
+	Loaded        bool   `json:"loaded"`        // whether the image has finished loading successfully
+}
+
+type FormField struct {
+	Name     string `json:"name"`     // name attribute of the field
+	Type     string `json:"type"`     // type of the field (e.g. text, checkbox, select)
+	Value    string `json:"value"`    // current value of the field
+	Required bool   `json:"required"` // whether the field is required
+}
+
+type AXNode struct {
+	Role     string   `json:"role"`               // accessibility role of the node (e.g. button, link, heading)
+	Name     string   `json:"name"`               // accessible name of the node
+	Value    string   `json:"value,omitempty"`    // current value of the node (e.g. text field contents)
+	Children []AXNode `json:"children,omitempty"` // child nodes
+}
+
+// marshalWithExtra marshals typed value adding extra keys at the top level unless they collide with typed fields
+func marshalWithExtra(typed any, extra map[string]any) ([]byte, error) {
+	bytes, err := json.Marshal(typed)
+	if err != nil || len(extra) == 0 {
+		return bytes, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(bytes, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if _, found := fields[key]; found {
+			continue
+		}
+		valueBytes, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = valueBytes
+	}
+	return json.Marshal(fields)
+}
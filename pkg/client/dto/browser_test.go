@@ -0,0 +1,131 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+)
+
+func TestBrowserOptsNetworkThrottle(t *testing.T) {
+	RegisterTestingT(t)
+
+	bytes, err := json.Marshal(Config{
+		Browser: BrowserOpts{
+			Program:         "navigate('https://example.com')",
+			NetworkThrottle: lo.ToPtr(Slow3G),
+		},
+	})
+	Expect(err).To(BeNil())
+
+	var out map[string]any
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	Expect(out["browser"].(map[string]any)["networkThrottle"]).To(Equal(map[string]any{
+		"offline":      false,
+		"downloadKbps": float64(400),
+		"uploadKbps":   float64(400),
+		"latencyMs":    float64(2000),
+	}))
+}
+
+func TestBrowserOptsInitScripts(t *testing.T) {
+	RegisterTestingT(t)
+
+	bytes, err := json.Marshal(BrowserOpts{
+		InitScripts: []string{"Object.defineProperty(navigator, 'webdriver', {get: () => undefined})"},
+	})
+	Expect(err).To(BeNil())
+
+	var out map[string]any
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	Expect(out["initScripts"]).To(Equal([]any{"Object.defineProperty(navigator, 'webdriver', {get: () => undefined})"}))
+}
+
+func TestBrowserOptsStealth(t *testing.T) {
+	RegisterTestingT(t)
+
+	bytes, err := json.Marshal(Config{
+		Browser: BrowserOpts{
+			Stealth:        lo.ToPtr(true),
+			StealthOptions: &StealthOptions{WebGLVendor: lo.ToPtr(false)},
+		},
+	})
+	Expect(err).To(BeNil())
+
+	var out map[string]any
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	browser := out["browser"].(map[string]any)
+	Expect(browser["stealth"]).To(Equal(true))
+	Expect(browser["stealthOptions"]).To(Equal(map[string]any{"webglVendor": false}))
+
+	bytes, err = json.Marshal(BrowserOpts{})
+	Expect(err).To(BeNil())
+	Expect(string(bytes)).To(ContainSubstring(`"stealth":null`))
+}
+
+func TestExtraOptions(t *testing.T) {
+	RegisterTestingT(t)
+
+	bytes, err := json.Marshal(Config{
+		Browser: BrowserOpts{
+			Program: "navigate('https://example.com')",
+			Extra:   map[string]any{"newFeature": true, "program": "ignored"},
+		},
+	})
+	Expect(err).To(BeNil())
+
+	var out map[string]any
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	browser := out["browser"].(map[string]any)
+	Expect(browser["newFeature"]).To(Equal(true))
+	Expect(browser["program"]).To(Equal("navigate('https://example.com')"))
+
+	bytes, err = json.Marshal(&BrowserMessageIn{
+		SessionID: "session",
+		Extra:     map[string]any{"trace": map[string]any{"id": "abc"}},
+	})
+	Expect(err).To(BeNil())
+	out = map[string]any{}
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	Expect(out["sessionID"]).To(Equal("session"))
+	Expect(out["trace"]).To(Equal(map[string]any{"id": "abc"}))
+}
+
+func TestBrowserOptsMediaEmulation(t *testing.T) {
+	RegisterTestingT(t)
+
+	bytes, err := json.Marshal(BrowserOpts{ColorScheme: ColorSchemeDark, ReducedMotion: lo.ToPtr(true)})
+	Expect(err).To(BeNil())
+
+	var out map[string]any
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	Expect(out["colorScheme"]).To(Equal("dark"))
+	Expect(out["reducedMotion"]).To(Equal(true))
+}
+
+func TestBrowserOptsLocale(t *testing.T) {
+	RegisterTestingT(t)
+
+	bytes, err := json.Marshal(BrowserOpts{
+		Locale:    "de-DE",
+		Languages: []string{"de-DE", "de", "en"},
+	})
+	Expect(err).To(BeNil())
+
+	var out map[string]any
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	Expect(out["locale"]).To(Equal("de-DE"))
+	Expect(out["languages"]).To(Equal([]any{"de-DE", "de", "en"}))
+}
+
+func TestBrowserOptsGrantPermissions(t *testing.T) {
+	RegisterTestingT(t)
+
+	bytes, err := json.Marshal(BrowserOpts{GrantPermissions: []string{"geolocation", "notifications"}})
+	Expect(err).To(BeNil())
+
+	var out map[string]any
+	Expect(json.Unmarshal(bytes, &out)).To(Succeed())
+	Expect(out["grantPermissions"]).To(Equal([]any{"geolocation", "notifications"}))
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+// ErrManualInterventionRequired is returned when the page requires manual intervention (e.g. CAPTCHA)
+// whilst the browser runs headless, so nobody can resolve it
+var ErrManualInterventionRequired = errors.New("manual intervention required")
+
+// ErrNoActiveSession is returned when a command is sent before the session has been started
+var ErrNoActiveSession = errors.New("no active session: browser session has not been started")
+
+// ErrProgramTooLarge is returned (without sending it) when program exceeds Config.MaxProgramBytes
+var ErrProgramTooLarge = errors.New("program too large")
+
+var (
+	// ErrTimeout is matched (with errors.Is) by errors caused by a command or a wait timing out
+	ErrTimeout = errors.New("timeout")
+	// ErrElementNotFound is matched (with errors.Is) by errors caused by a missing element
+	ErrElementNotFound = errors.New("element not found")
+	// ErrNavigation is matched (with errors.Is) by errors caused by a failed navigation (e.g. DNS or TLS failures)
+	ErrNavigation = errors.New("navigation failed")
+	// ErrSessionExpired is matched (with errors.Is) by errors caused by the backend session being gone (e.g. reaped after timeout)
+	ErrSessionExpired = errors.New("session expired")
+)
+
+var sessionExpiredFragments = []string{"session not found", "session expired", "no such session", "unknown session"}
+
+// CommandError is an error reported by the backend, classified by errors.Is into ErrTimeout, ErrElementNotFound,
+// ErrNavigation or ErrSessionExpired (if recognized), Error() returns the raw message of the backend
+type CommandError struct {
+	Message string  // raw error message returned by the backend
+	Kinds   []error // matching kinds (ErrTimeout, ErrElementNotFound, ErrNavigation, ErrSessionExpired), empty if not recognized
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+func (e *CommandError) Is(target error) bool {
+	return lo.Contains(e.Kinds, target)
+}
+
+// errorKinds maps well-known fragments of the backend errors (lowercase) to their kinds, an error matches every kind
+// with a matching fragment, so that e.g. timeout of waiting for a selector is both a missing element and a timeout
+var errorKinds = []struct {
+	kind      error
+	fragments []string
+}{
+	{ErrSessionExpired, sessionExpiredFragments},
+	{ErrElementNotFound, []string{"element not found", "no element", "no node found", "waiting for selector", "failed to find element", "could not find element"}},
+	{ErrNavigation, []string{"net::err_", "navigation failed", "failed to navigate", "navigation timeout"}},
+	{ErrTimeout, []string{"timeout", "timed out", "deadline exceeded"}},
+}
+
+// newCommandError classifies error message returned by the backend
+func newCommandError(msg string) *CommandError {
+	lower := strings.ToLower(msg)
+	err := &CommandError{Message: msg}
+	for _, errorKind := range errorKinds {
+		if lo.SomeBy(errorKind.fragments, func(fragment string) bool { return strings.Contains(lower, fragment) }) {
+			err.Kinds = append(err.Kinds, errorKind.kind)
+		}
+	}
+	return err
+}
+
+// BaasError is returned by program commands when additional diagnostics were collected for a failure
+type BaasError struct {
+	Err        error  // original error returned by the command
+	Screenshot []byte // screenshot of the page taken right after the failure (if available)
+}
+
+func (e *BaasError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BaasError) Unwrap() error {
+	return e.Err
+}
+
+// LlmError is returned by LLM actions (e.g. LlmClick) with diagnostics of what the LLM saw
+type LlmError struct {
+	Err        error  // original error returned by the action
+	Screenshot []byte // screenshot of the page returned with the failure (if the session returns screenshots)
+	Reasoning  string // reasoning of the LLM (if returned by the backend)
+}
+
+func (e *LlmError) Error() string {
+	if e.Reasoning == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + " (LLM reasoning: " + e.Reasoning + ")"
+}
+
+func (e *LlmError) Unwrap() error {
+	return e.Err
+}
+
+// staleElementFragments are errors of the backend (lowercase) for an element removed from DOM after its lookup
+var staleElementFragments = []string{
+	"stale element reference",
+	"element is not attached to the page document",
+	"node is detached from document",
+	"node with given id does not belong to the document",
+}
+
+// isStaleElementError returns true if the backend failed the command because the element was re-rendered
+// (detached from DOM) between its lookup and the action
+func isStaleElementError(err error) bool {
+	var commandErr *CommandError
+	if !errors.As(err, &commandErr) {
+		return false
+	}
+	msg := strings.ToLower(commandErr.Message)
+	return lo.SomeBy(staleElementFragments, func(fragment string) bool { return strings.Contains(msg, fragment) })
+}
+
+// isElementAppearedError returns true if the backend failed assertGoneFor because the element showed up
+// (as opposed to transport or session failures)
+func isElementAppearedError(err error) bool {
+	var commandErr *CommandError
+	return errors.As(err, &commandErr) && strings.Contains(strings.ToLower(commandErr.Message), "appeared")
+}
+
+// isSessionExpiredError returns true if the error was caused by the backend session being gone,
+// including transport-level errors (e.g. 404 response for an unknown session) which are not classified
+func isSessionExpiredError(err error) bool {
+	if errors.Is(err, ErrSessionExpired) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range sessionExpiredFragments {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func TestCommandErrorClassification(t *testing.T) {
+	RegisterTestingT(t)
+
+	for msg, kinds := range map[string][]error{
+		"Element not found: #submit":                                      {ErrElementNotFound},
+		"waiting for selector `#submit` failed: timeout 30000ms exceeded": {ErrElementNotFound, ErrTimeout},
+		"No node found for selector: .price":                              {ErrElementNotFound},
+		"net::ERR_NAME_NOT_RESOLVED at https://example.invalid":           {ErrNavigation},
+		"Navigation timeout of 30000 ms exceeded":                         {ErrNavigation, ErrTimeout},
+		"TimeoutError: timed out after 10s":                               {ErrTimeout},
+		"context deadline exceeded":                                       {ErrTimeout},
+		"session not found: 6f1c2a":                                       {ErrSessionExpired},
+	} {
+		err := newCommandError(msg)
+		Expect(err.Error()).To(Equal(msg))
+		for _, kind := range []error{ErrTimeout, ErrElementNotFound, ErrNavigation, ErrSessionExpired} {
+			Expect(errors.Is(err, kind)).To(Equal(lo.Contains(kinds, kind)), msg+" is "+kind.Error())
+		}
+	}
+
+	err := newCommandError("ReferenceError: foo is not defined")
+	Expect(errors.Is(err, ErrTimeout) || errors.Is(err, ErrElementNotFound) || errors.Is(err, ErrNavigation)).To(BeFalse())
+}
+
+func TestCommandErrorFromProgram(t *testing.T) {
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Error: "element not found: #missing"}, nil
+	}, WithScreenshotOnError("failure"))
+
+	err := p.Click("#missing")
+	Expect(errors.Is(err, ErrElementNotFound)).To(BeTrue())
+	var commandErr *CommandError
+	Expect(errors.As(err, &commandErr)).To(BeTrue())
+	Expect(commandErr.Message).To(Equal("element not found: #missing"))
+}
+
+func TestStaleElementError(t *testing.T) {
+	RegisterTestingT(t)
+
+	for msg, stale := range map[string]bool{
+		"stale element reference: element is not attached to the page document": true,
+		"Error: Node is detached from document":                                 true,
+		"Node with given id does not belong to the document":                    true,
+		"Element not found: #stale-banner":                                      false,
+		"Error: detached frame, cannot evaluate":                                false,
+	} {
+		Expect(isStaleElementError(newCommandError(msg))).To(Equal(stale), msg)
+	}
+	Expect(isStaleElementError(errors.New("stale element reference"))).To(BeFalse())
+}
+
+func TestMessageTimeoutError(t *testing.T) {
+	RegisterTestingT(t)
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	_, err := NewClient(server.URL, "key", time.Second, time.Minute).Message(context.Background(), dto.BrowserMessageIn{
+		SessionID: "test-session",
+		Program:   "getURL()",
+		Timeout:   "50ms",
+	})
+	Expect(errors.Is(err, ErrTimeout)).To(BeTrue())
+}
+
+func TestAutoRestartOnSessionExpired(t *testing.T) {
+	respond := func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		if msg.SessionID == "test-session" && msg.Program == "getURL()" {
+			return &dto.BrowserMessageOut{Error: "session not found: test-session"}, nil
+		}
+		if msg.Program == "getCookies()" {
+			return &dto.BrowserMessageOut{Value: []any{map[string]any{"name": "sid", "value": "42", "domain": "example.com"}}}, nil
+		}
+		return &dto.BrowserMessageOut{SessionID: msg.SessionID, Value: "https://example.com/" + msg.SessionID}, nil
+	}
+
+	p, c := newMockProgram(t, respond, WithAutoRestart())
+	_, err := p.GetCookies()
+	Expect(err).To(BeNil())
+
+	url, err := p.GetURL()
+	Expect(err).To(BeNil())
+	Expect(url).To(Equal("https://example.com/test-session-1"))
+	Expect(p.sessionID).To(Equal("test-session-1"))
+	Expect(c.programs).To(Equal([]string{"getCookies()", "getURL()", "getURL()"}))
+	Expect(c.started).To(HaveLen(1))
+	Expect(c.started[0].Browser.Cookies).To(Equal([]dto.BrowserCookie{{Name: "sid", Value: "42", Domain: "example.com"}}))
+
+	// restarted session keeps response captures and the last cookie snapshot
+	p, c = newMockProgram(t, respond, WithAutoRestart(), WithCookieTracking())
+	Expect(p.CaptureResponses(`/api/`, t.TempDir())).To(Succeed())
+	_, err = p.GetURL()
+	Expect(err).To(BeNil())
+	Expect(c.started).To(HaveLen(1))
+	Expect(c.started[0].Browser.CaptureResponses).To(Equal([]string{"/api/"}))
+	Expect(c.started[0].Browser.Cookies).To(Equal([]dto.BrowserCookie{{Name: "sid", Value: "42", Domain: "example.com"}}))
+
+	// without auto restart the error is returned and can be told apart
+	p, c = newMockProgram(t, respond)
+	_, err = p.GetURL()
+	Expect(errors.Is(err, ErrSessionExpired)).To(BeTrue())
+	Expect(c.started).To(BeEmpty())
+}
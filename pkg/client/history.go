@@ -0,0 +1,47 @@
+package client
+
+// History keeps programs sent from the TUI and navigates through them,
+// preserving the in-progress (not yet sent) line as a draft
+type History struct {
+	entries []string
+	offset  int // position counted from the end of entries (0 points to the draft)
+	draft   string
+}
+
+// Add appends entry to the history and resets navigation
+func (h *History) Add(entry string) {
+	h.entries = append(h.entries, entry)
+	h.Reset()
+}
+
+// Prev returns the previous (older) entry, current is the value being edited and is saved as the draft
+// when leaving it, returns false if there are no older entries
+func (h *History) Prev(current string) (string, bool) {
+	if h.offset >= len(h.entries) {
+		return current, false
+	}
+	if h.offset == 0 {
+		h.draft = current
+	}
+	h.offset++
+	return h.entries[len(h.entries)-h.offset], true
+}
+
+// Next returns the next (newer) entry or the draft when reaching the end of the history,
+// returns false if the draft is already being edited
+func (h *History) Next(current string) (string, bool) {
+	if h.offset == 0 {
+		return current, false
+	}
+	h.offset--
+	if h.offset == 0 {
+		return h.draft, true
+	}
+	return h.entries[len(h.entries)-h.offset], true
+}
+
+// Reset moves navigation back to the (empty) draft
+func (h *History) Reset() {
+	h.offset = 0
+	h.draft = ""
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHistoryNavigation(t *testing.T) {
+	RegisterTestingT(t)
+
+	var h History
+	_, ok := h.Prev("draft")
+	Expect(ok).To(BeFalse())
+
+	h.Add("first")
+	h.Add("second")
+	h.Add("third")
+
+	expectMove := func(value string, ok bool) func(expected string) {
+		return func(expected string) {
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal(expected))
+		}
+	}
+
+	expectMove(h.Prev("typing"))("third")
+	expectMove(h.Prev("third"))("second")
+	expectMove(h.Prev("second"))("first")
+	_, ok = h.Prev("first")
+	Expect(ok).To(BeFalse())
+
+	expectMove(h.Next("first"))("second")
+	expectMove(h.Next("second"))("third")
+	expectMove(h.Next("third"))("typing")
+	value, ok := h.Next("typing")
+	Expect(ok).To(BeFalse())
+	Expect(value).To(Equal("typing"))
+}
+
+func TestHistoryEditThenNavigate(t *testing.T) {
+	RegisterTestingT(t)
+
+	var h History
+	h.Add("first")
+	h.Add("second")
+
+	value, _ := h.Prev("")
+	Expect(value).To(Equal("second"))
+
+	// editing a recalled entry doesn't break navigation nor replace the draft
+	value, _ = h.Prev("second edited")
+	Expect(value).To(Equal("first"))
+	value, _ = h.Next("first")
+	Expect(value).To(Equal("second"))
+	value, _ = h.Next("second")
+	Expect(value).To(Equal(""))
+
+	// sending resets navigation to the most recent entry
+	h.Add("second edited")
+	value, _ = h.Prev("new draft")
+	Expect(value).To(Equal("second edited"))
+	value, _ = h.Next("second edited")
+	Expect(value).To(Equal("new draft"))
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpbinCompleteForm(t *testing.T) {
+	p, cancel := newLocalDebugProgram(t)
+	defer cancel()
+
+	s, err := p.NavigateStatus("https://httpbin.org/forms/post")
+	Expect(err).To(BeNil())
+	Expect(s).To(Equal(200))
+
+	err = p.CompleteForm("form", map[string]string{
+		"custname":  "John Doe",
+		"custemail": "john@example.com",
+	}, true)
+	Expect(err).To(BeNil())
+
+	url, err := p.GetURL()
+	Expect(err).To(BeNil())
+	Expect(url).To(Equal("https://httpbin.org/post"))
+}
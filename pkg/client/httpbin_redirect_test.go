@@ -0,0 +1,18 @@
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpbinNavigateResult(t *testing.T) {
+	p, cancel := newLocalDebugProgram(t)
+	defer cancel()
+
+	result, err := p.NavigateResult("https://httpbin.org/redirect/2")
+	Expect(err).To(BeNil())
+	Expect(result.Status).To(Equal(200))
+	Expect(result.URL).To(Equal("https://httpbin.org/get"))
+	Expect(result.RedirectChain).To(HaveLen(2))
+}
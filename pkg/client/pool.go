@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+// DefaultPoolIdleLifetime is how long a warm session may stay idle in the pool before it is replaced
+const DefaultPoolIdleLifetime = 5 * time.Minute
+
+// ErrPoolClosed is returned when acquiring a session from the closed pool
+var ErrPoolClosed = errors.New("pool is closed")
+
+// ProgramFactory starts a new session bound to ctx (the session is terminated when ctx is cancelled),
+// e.g. func(ctx context.Context) (Program, error) { return NewProgram(ctx, cfg, reporter) }
+type ProgramFactory func(ctx context.Context) (Program, error)
+
+type PoolOption func(p *Pool)
+
+// WithPoolIdleLifetime sets how long a warm session may stay idle before it is replaced
+// (it should be lower than the session timeout)
+func WithPoolIdleLifetime(lifetime time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.idleLifetime = lifetime
+	}
+}
+
+// Pool maintains warm sessions to hand them out without cold start latency (expired and failed sessions are replaced
+// in background), amount of sessions (both idle and acquired) never exceeds the pool size
+type Pool struct {
+	ctx          context.Context
+	cancel       context.CancelFunc
+	factory      ProgramFactory
+	size         int
+	idleLifetime time.Duration
+
+	mu       sync.Mutex
+	total    int // idle, acquired and starting sessions
+	idle     []*pooledProgram
+	acquired map[Program]*pooledProgram
+	released chan struct{} // closed (and replaced) whenever a session is released or discarded
+	closed   bool
+	startErr error // error of the last background start, returned by the next Acquire
+}
+
+type pooledProgram struct {
+	program   Program
+	cancel    context.CancelFunc
+	idleSince time.Time
+}
+
+// NewPool creates pool of size sessions and starts all of them
+func NewPool(ctx context.Context, size int, factory ProgramFactory, opts ...PoolOption) (*Pool, error) {
+	if size <= 0 {
+		return nil, errors.Errorf("invalid pool size %d: must be positive", size)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pool{
+		ctx:          ctx,
+		cancel:       cancel,
+		factory:      factory,
+		size:         size,
+		idleLifetime: DefaultPoolIdleLifetime,
+		acquired:     map[Program]*pooledProgram{},
+		released:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.idleLifetime <= 0 {
+		cancel()
+		return nil, errors.Errorf("invalid pool idle lifetime %s: must be positive", p.idleLifetime)
+	}
+
+	errs := make(chan error, size)
+	for i := 0; i < size; i++ {
+		go func() {
+			errs <- p.refill()
+		}()
+	}
+	for i := 0; i < size; i++ {
+		if err := <-errs; err != nil {
+			p.Close()
+			return nil, errors.Wrapf(err, "failed to start pool")
+		}
+	}
+	go p.maintain()
+	return p, nil
+}
+
+// Acquire returns a warm session (starting a new one if there are no idle sessions), it blocks while all sessions
+// of the pool are acquired, the session must be returned with Release; ctx limits waiting (including a cold start),
+// not the lifetime of the session. If a session failed to start in background, its error is returned once
+func (p *Pool) Acquire(ctx context.Context) (Program, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if err := p.startErr; err != nil {
+			p.startErr = nil
+			p.mu.Unlock()
+			return nil, errors.Wrapf(err, "failed to replace pool session")
+		}
+		for len(p.idle) > 0 {
+			session := p.idle[len(p.idle)-1]
+			p.idle = p.idle[:len(p.idle)-1]
+			if p.usable(session) {
+				p.acquired[session.program] = session
+				p.mu.Unlock()
+				return session.program, nil
+			}
+			p.discardLocked(session)
+		}
+		if p.total < p.size {
+			p.total++
+			p.mu.Unlock()
+			session, err := p.start(ctx)
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if err != nil {
+				p.total--
+				p.notifyLocked()
+				return nil, err
+			}
+			p.acquired[session.program] = session
+			return session.program, nil
+		}
+		released := p.released
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// Release returns the acquired session to the pool, failed sessions are terminated and replaced in background
+func (p *Pool) Release(program Program) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	session, found := p.acquired[program]
+	if !found {
+		return
+	}
+	delete(p.acquired, program)
+	if p.closed || program.Error() != nil {
+		p.discardLocked(session)
+		if !p.closed {
+			go p.refillInBackground()
+		}
+		return
+	}
+	session.idleSince = time.Now()
+	p.idle = append(p.idle, session)
+	p.notifyLocked()
+}
+
+// Close terminates all sessions of the pool (including acquired ones)
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, session := range p.idle {
+		p.discardLocked(session)
+	}
+	p.idle = nil
+	p.cancel()
+}
+
+// maintain replaces expired and failed idle sessions (and sessions which failed to start) until the pool is closed
+func (p *Pool) maintain() {
+	// sessions are checked twice per idle lifetime, but not more often than every millisecond
+	ticker := time.NewTicker(max(p.idleLifetime/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.idle = lo.Filter(p.idle, func(session *pooledProgram, _ int) bool {
+				if p.usable(session) {
+					return true
+				}
+				p.discardLocked(session)
+				return false
+			})
+			missing := p.size - p.total
+			p.mu.Unlock()
+			for i := 0; i < missing; i++ {
+				p.refillInBackground()
+			}
+		}
+	}
+}
+
+func (p *Pool) usable(session *pooledProgram) bool {
+	return session.program.Error() == nil && time.Since(session.idleSince) < p.idleLifetime
+}
+
+// refillInBackground refills the pool keeping the error for the next Acquire
+func (p *Pool) refillInBackground() {
+	if err := p.refill(); err != nil {
+		p.mu.Lock()
+		p.startErr = err
+		p.mu.Unlock()
+	}
+}
+
+// refill starts a new idle session unless the pool is full
+func (p *Pool) refill() error {
+	p.mu.Lock()
+	if p.closed || p.total >= p.size {
+		p.mu.Unlock()
+		return nil
+	}
+	p.total++
+	p.mu.Unlock()
+
+	session, err := p.start(p.ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.total--
+		p.notifyLocked()
+		return err
+	}
+	session.idleSince = time.Now()
+	p.idle = append(p.idle, session)
+	p.notifyLocked()
+	return nil
+}
+
+// start starts a new session bound to the pool, cancelling ctx aborts the start
+func (p *Pool) start(ctx context.Context) (*pooledProgram, error) {
+	sessionCtx, cancel := context.WithCancel(p.ctx)
+	stop := context.AfterFunc(ctx, cancel)
+	program, err := p.factory(sessionCtx)
+	if !stop() {
+		cancel()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		cancel()
+		return nil, errors.Wrapf(err, "failed to start session")
+	}
+	return &pooledProgram{program: program, cancel: cancel}, nil
+}
+
+func (p *Pool) discardLocked(session *pooledProgram) {
+	session.cancel()
+	p.total--
+	p.notifyLocked()
+}
+
+func (p *Pool) notifyLocked() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+type testProgramFactory struct {
+	mu      sync.Mutex
+	started []*program
+}
+
+func (f *testProgramFactory) start(ctx context.Context) (Program, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := &program{
+		client:    &mockClient{},
+		ctx:       ctx,
+		sessionID: fmt.Sprintf("session-%d", len(f.started)+1),
+		reporter:  &testReporter{},
+	}
+	f.started = append(f.started, p)
+	return p, nil
+}
+
+func (f *testProgramFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.started)
+}
+
+func TestPoolReuse(t *testing.T) {
+	RegisterTestingT(t)
+
+	factory := &testProgramFactory{}
+	pool, err := NewPool(context.Background(), 1, factory.start)
+	Expect(err).To(BeNil())
+	defer pool.Close()
+	Expect(factory.count()).To(Equal(1))
+
+	first, err := pool.Acquire(context.Background())
+	Expect(err).To(BeNil())
+
+	// pool is bounded, so the next acquire waits for a release
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx)
+	Expect(err).To(Equal(context.DeadlineExceeded))
+
+	pool.Release(first)
+	second, err := pool.Acquire(context.Background())
+	Expect(err).To(BeNil())
+	Expect(second).To(BeIdenticalTo(first))
+	Expect(factory.count()).To(Equal(1))
+}
+
+func TestPoolIdleLifetime(t *testing.T) {
+	RegisterTestingT(t)
+
+	factory := &testProgramFactory{}
+	_, err := NewPool(context.Background(), 1, factory.start, WithPoolIdleLifetime(0))
+	Expect(err).NotTo(BeNil())
+	Expect(factory.count()).To(Equal(0))
+
+	pool, err := NewPool(context.Background(), 1, factory.start, WithPoolIdleLifetime(time.Nanosecond))
+	Expect(err).To(BeNil())
+	pool.Close()
+}
+
+func TestPoolReplacesFailedSession(t *testing.T) {
+	RegisterTestingT(t)
+
+	factory := &testProgramFactory{}
+	pool, err := NewPool(context.Background(), 2, factory.start)
+	Expect(err).To(BeNil())
+	defer pool.Close()
+
+	failed, err := pool.Acquire(context.Background())
+	Expect(err).To(BeNil())
+	failed.(*program).err = errors.New("browser crashed")
+	pool.Release(failed)
+	Eventually(factory.count).Should(Equal(3))
+	Expect(failed.(*program).ctx.Err()).To(Equal(context.Canceled))
+
+	for i := 0; i < 2; i++ {
+		p, err := pool.Acquire(context.Background())
+		Expect(err).To(BeNil())
+		Expect(p).NotTo(BeIdenticalTo(failed))
+	}
+	Expect(factory.count()).To(Equal(3))
+}
+
+func TestPoolReplacesExpiredSession(t *testing.T) {
+	RegisterTestingT(t)
+
+	factory := &testProgramFactory{}
+	pool, err := NewPool(context.Background(), 1, factory.start, WithPoolIdleLifetime(20*time.Millisecond))
+	Expect(err).To(BeNil())
+	defer pool.Close()
+
+	time.Sleep(30 * time.Millisecond)
+	p, err := pool.Acquire(context.Background())
+	Expect(err).To(BeNil())
+	Expect(p.(*program).sessionID).To(Equal("session-2"))
+	Expect(factory.started[0].ctx.Err()).To(Equal(context.Canceled))
+
+	pool.Close()
+	_, err = pool.Acquire(context.Background())
+	Expect(err).To(Equal(ErrPoolClosed))
+}
+
+func TestPoolReplacesExpiredSessionInBackground(t *testing.T) {
+	RegisterTestingT(t)
+
+	factory := &testProgramFactory{}
+	pool, err := NewPool(context.Background(), 1, factory.start, WithPoolIdleLifetime(20*time.Millisecond))
+	Expect(err).To(BeNil())
+	defer pool.Close()
+
+	// no Acquire needed, the pool stays warm on its own
+	Eventually(factory.count).Should(BeNumerically(">=", 2))
+	Expect(factory.started[0].ctx.Err()).To(Equal(context.Canceled))
+}
+
+func TestPoolReportsBackgroundStartError(t *testing.T) {
+	RegisterTestingT(t)
+
+	factory := &testProgramFactory{}
+	var failing atomic.Bool
+	pool, err := NewPool(context.Background(), 1, func(ctx context.Context) (Program, error) {
+		if failing.Load() {
+			return nil, errors.New("backend unavailable")
+		}
+		return factory.start(ctx)
+	})
+	Expect(err).To(BeNil())
+	defer pool.Close()
+
+	failed, err := pool.Acquire(context.Background())
+	Expect(err).To(BeNil())
+	failed.(*program).err = errors.New("browser crashed")
+	failing.Store(true)
+	pool.Release(failed)
+
+	Eventually(func() error {
+		_, err := pool.Acquire(context.Background())
+		return err
+	}).Should(MatchError(ContainSubstring("failed to replace pool session: failed to start session: backend unavailable")))
+}
+
+func TestPoolAcquireCancelsColdStart(t *testing.T) {
+	RegisterTestingT(t)
+
+	var blocking atomic.Bool
+	factory := &testProgramFactory{}
+	pool, err := NewPool(context.Background(), 2, func(ctx context.Context) (Program, error) {
+		if blocking.Load() {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return factory.start(ctx)
+	}, WithPoolIdleLifetime(time.Hour))
+	Expect(err).To(BeNil())
+	defer pool.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err := pool.Acquire(context.Background())
+		Expect(err).To(BeNil())
+	}
+	first := factory.started[0]
+	blocking.Store(true)
+	pool.Release(first)
+	first.err = errors.New("browser crashed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err = pool.Acquire(ctx)
+	Expect(err).To(Equal(context.DeadlineExceeded))
+	Expect(time.Since(started)).To(BeNumerically("<", time.Second))
+}
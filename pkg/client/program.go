@@ -2,9 +2,17 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/savioxavier/termlink"
@@ -13,6 +21,7 @@ import (
 	"github.com/samber/lo"
 
 	"github.com/integrail/baas-client/pkg/client/dto"
+	"github.com/integrail/baas-client/pkg/util"
 )
 
 type ActionOption func(args []string) []string
@@ -65,49 +74,166 @@ func WithIframe(selector string) ActionOption {
 	}
 }
 
+// WithCookieDomain limits cookie commands to cookies of the domain
+func WithCookieDomain(domain string) ActionOption {
+	return func(args []string) []string {
+		return append(args, fmt.Sprintf("domain:%s", domain))
+	}
+}
+
+// WithDragToValue makes SetRange drag the handle of a custom (non-native) slider to the position of the value
+func WithDragToValue() ActionOption {
+	return func(args []string) []string {
+		return append(args, "dragToValue")
+	}
+}
+
+// WithNoProxy makes the backend use a direct connection (bypassing Config.UseProxy/UseRandomProxy) for the command's
+// navigation, e.g. to reach an internal health URL; requires backend support, older backends ignore the option
+func WithNoProxy() ActionOption {
+	return func(args []string) []string {
+		return append(args, "noProxy")
+	}
+}
+
+// WithPierceShadow makes selectors match elements inside (open) shadow roots, e.g. for Click, GetInnerText or WaitVisible
+// in web-component-heavy apps; it is slower as the backend has to walk every shadow tree of the page
+func WithPierceShadow() ActionOption {
+	return func(args []string) []string {
+		return append(args, "pierceShadow")
+	}
+}
+
+// WithRegexMatch makes assertions treat expected value as a regular expression (it is not sent to the backend)
+func WithRegexMatch() ActionOption {
+	return func(args []string) []string {
+		return append(args, regexMatchArg)
+	}
+}
+
+const regexMatchArg = "regexMatch"
+
 type Program interface {
 	Error() error
 	NavigateStatus(url string, opts ...ActionOption) (int, error)
+	NavigateResult(url string, opts ...ActionOption) (dto.NavigationResult, error)
+	GetRedirectChain(url string, opts ...ActionOption) ([]dto.RedirectHop, error)
+	LastNavigationStatus(opts ...ActionOption) (int, error)
 	TakeScreenshot(name string, opts ...ActionOption) ([]byte, error)
 	LlmSetValue(desc, value string, opts ...ActionOption) error
 	LlmSetValueSkipVerify(desc, value string, opts ...ActionOption) error
 	LlmLogin(username, password string, opts ...ActionOption) error
+	LlmIsLoginPage(opts ...ActionOption) (bool, error)
 	GetURL(opts ...ActionOption) (string, error)
+	GetPageTitle(opts ...ActionOption) (string, error)
+	GetTitle(opts ...ActionOption) (string, error)
+	SelectText(selector string, opts ...ActionOption) error
+	GetSelectedText(opts ...ActionOption) (string, error)
+	SetTitle(title string, opts ...ActionOption) error
+	GetPageMeta(opts ...ActionOption) (dto.PageMeta, error)
+	GetImages(opts ...ActionOption) ([]dto.ImageInfo, error)
+	GetElementMetrics(selector string, opts ...ActionOption) (dto.ElementMetrics, error)
+	GetCookies(opts ...ActionOption) ([]dto.BrowserCookie, error)
+	SetCookies(cookies []dto.BrowserCookie, opts ...ActionOption) error
+	CookieChanges() []dto.CookieDelta
+	Info(opts ...ActionOption) (dto.SessionInfo, error)
+	GetCapabilities(opts ...ActionOption) (dto.SessionCapabilities, error)
 	Click(selector string, opts ...ActionOption) error
 	ClickN(selector string, index int, opts ...ActionOption) error
+	ClickIfPresent(selector string, opts ...ActionOption) (bool, error)
 	GetSecret(name string, opts ...ActionOption) (string, error)
 	GetValue(name string, opts ...ActionOption) (string, error)
 	OuterHtml(selector string, opts ...ActionOption) (string, error)
+	GetPageSource(opts ...ActionOption) (string, error)
+	GetNavigationBody(opts ...ActionOption) (string, error)
 	InnerHtml(selector string, opts ...ActionOption) (string, error)
+	GetAttribute(selector, name string, opts ...ActionOption) (string, error)
+	AssertAttribute(selector, name, expected string, opts ...ActionOption) error
+	AssertGoneFor(selector string, duration string, opts ...ActionOption) error
 	IsElementPresent(selector string, opts ...ActionOption) (bool, error)
+	IsLoading(opts ...ActionOption) (bool, error)
+	IsElementPresentAnyFrame(selector string, opts ...ActionOption) (bool, string, error)
 	CountElements(selector string, opts ...ActionOption) (int, error)
 	LlmClick(description string, opts ...ActionOption) error
 	LlmClickElement(elems []string, description string, opts ...ActionOption) error
 	LlmSendKeys(description, value string, opts ...ActionOption) error
 	LlmText(description string, opts ...ActionOption) (string, error)
+	LlmReadImage(selector, question string, opts ...ActionOption) (string, error)
 	Log(message string, opts ...ActionOption) error
 	LogURL(opts ...ActionOption) error
+	GetConsoleErrors(opts ...ActionOption) ([]string, error)
 	Navigate(url string, opts ...ActionOption) error
 	Reload(opts ...ActionOption) error
+	ResetBrowserState(opts ...ActionOption) error
 	ScrollToBottom(opts ...ActionOption) error
+	ScrollElement(selector string, x, y int, opts ...ActionOption) error
+	ScrollTo(x, y int, opts ...ActionOption) error
+	GetScrollPosition(opts ...ActionOption) (x, y float64, err error)
 	EvaluateJS(script string, opts ...ActionOption) (any, error)
+	Assert(expression string, opts ...ActionOption) error
 	ReplaceInnerHtml(selector, html string, opts ...ActionOption) error
 	GetElementValueN(selector string, index int, opts ...ActionOption) (string, error)
 	SetValueN(selector string, index int, value string, opts ...ActionOption) error
+	SetRange(selector string, value float64, opts ...ActionOption) error
+	SetDate(selector string, t time.Time, opts ...ActionOption) error
+	SetDateTime(selector string, t time.Time, opts ...ActionOption) error
 	GetInnerText(selector string, opts ...ActionOption) (string, error)
+	WaitAndGetText(selector string, opts ...ActionOption) (string, error)
 	SendKeysToElement(selector string, keys string, opts ...ActionOption) error
 	SendKeys(text string, opts ...ActionOption) error
+	Paste(selector, text string, opts ...ActionOption) error
 	Sleep(duration string, opts ...ActionOption) error
 	Submit(selector string, opts ...ActionOption) error
 	Text(selector string, opts ...ActionOption) (string, error)
 	WaitFileDownload(duration string, opts ...ActionOption) (bool, error)
+	GetPendingDownloadName(opts ...ActionOption) (string, error)
 	ExecuteAndDownloadFile(program string, fileName string, waitStarted, waitDownloaded string, opts ...ActionOption) ([]byte, error)
 	DownloadFile(fileName string, waitStarted, waitDownloaded string, opts ...ActionOption) ([]byte, error)
+	Downloads() []dto.DownloadedFile
+	SaveAllDownloads(dir string) error
+	CaptureResponses(pattern string, dir string, opts ...ActionOption) error
+	GetWebSocketMessages(urlPattern string, opts ...ActionOption) ([]dto.WSMessage, error)
+	GetNetworkStats(opts ...ActionOption) (dto.NetworkStats, error)
 	WaitReady(selector string, opts ...ActionOption) error
 	WaitVisible(selector string, opts ...ActionOption) error
+	WaitForStable(selector string, quietMs int, opts ...ActionOption) error
+	WaitForTextStable(selector string, quietMs int, opts ...ActionOption) (string, error)
+	WaitForFonts(timeout string, opts ...ActionOption) error
+	WaitForAny(selectors []string, opts ...ActionOption) (string, error)
+	WaitForTextMatch(selector, pattern string, opts ...ActionOption) error
+	WaitForPopup(timeout string, opts ...ActionOption) (string, error)
+	SwitchTab(tabID string, opts ...ActionOption) error
+	WaitForManualIntervention(promptSelector string, timeout string, opts ...ActionOption) error
+	WaitForCookie(name string, timeout string, opts ...ActionOption) (dto.BrowserCookie, error)
 	SaveScreenshot(name string, fileName string, opts ...ActionOption) error
+	AssertScreenshotMatches(name, baselinePath string, opts ...ActionOption) error
+	VisualCheckpoint(name string, opts ...ActionOption) error
+	SaveSnapshot(fileName string, opts ...ActionOption) error
 	FindVisibleElements(elements []string, attributeName string, opts ...ActionOption) (string, error)
 	Execute(program string, opts ...ActionOption) (any, error)
+	ExecuteInFrame(frameIndex int, program string, opts ...ActionOption) (any, error)
+	ForEachURL(urls []string, program string, navigateOpts []ActionOption, opts ...ActionOption) ([]dto.URLResult, error)
+	Paginate(nextSelector string, maxPages int, extract func(p Program) error, opts ...ActionOption) (int, error)
+	ExecuteString(program string, opts ...ActionOption) (string, error)
+	ExecuteInt(program string, opts ...ActionOption) (int, error)
+	ExecuteFloat(program string, opts ...ActionOption) (float64, error)
+	ExecuteBool(program string, opts ...ActionOption) (bool, error)
+	ExecuteJSON(program string, v any, opts ...ActionOption) error
+	SetNetworkConditions(downloadKbps, uploadKbps int, latencyMs int, opts ...ActionOption) error
+	SetOffline(offline bool, opts ...ActionOption) error
+	SetWindowBounds(x, y, width, height int, opts ...ActionOption) error
+	SetColorScheme(scheme string, opts ...ActionOption) error
+	SetLocale(locale string, opts ...ActionOption) error
+	SetPermission(origin, name, state string, opts ...ActionOption) error
+	ClearPermissions(opts ...ActionOption) error
+	SetReducedMotion(reduce bool, opts ...ActionOption) error
+	EmulatePrintMedia(enable bool, opts ...ActionOption) error
+	AddInitScript(script string, opts ...ActionOption) error
+	GetAccessibilityTree(opts ...ActionOption) (dto.AXNode, error)
+	ExtractTable(selector string, opts ...ActionOption) ([][]string, error)
+	ExtractTableCSV(selector, fileName string, opts ...ActionOption) error
+	GetFormFields(formSelector string, opts ...ActionOption) ([]dto.FormField, error)
+	CompleteForm(formSelector string, values map[string]string, submit bool, opts ...ActionOption) error
 	DragAndDropBySelectors(from, to string, opts ...ActionOption) error
 }
 
@@ -115,18 +241,63 @@ type Reporter interface {
 	Report(msg string)
 }
 
+// ScreenshotReporter can be implemented by Reporter to receive screenshots returned by commands
+// (see WithReportScreenshots)
+type ScreenshotReporter interface {
+	Screenshot(name string, data []byte)
+}
+
 type Config struct {
-	UseProxy       bool                `json:"useProxy" yaml:"useProxy"`
-	LocalDebug     bool                `json:"localDebug" yaml:"localDebug"`
-	Url            string              `json:"url" yaml:"url"`
-	ApiKey         string              `json:"apiKey" yaml:"apiKey"`
-	Timeout        string              `json:"timeout" yaml:"timeout"`
-	MessageTimeout string              `json:"messageTimeout" yaml:"messageTimeout"`
-	Secrets        []string            `json:"secrets" yaml:"secrets"`
-	Values         []string            `json:"values" yaml:"values"`
-	Cookies        []dto.BrowserCookie `json:"cookies" yaml:"cookies"`
+	UseProxy           bool                   `json:"useProxy" yaml:"useProxy"`
+	LocalDebug         bool                   `json:"localDebug" yaml:"localDebug"`
+	Url                string                 `json:"url" yaml:"url"`
+	ApiKey             string                 `json:"apiKey" yaml:"apiKey"`
+	Timeout            string                 `json:"timeout" yaml:"timeout"`
+	MessageTimeout     string                 `json:"messageTimeout" yaml:"messageTimeout"`
+	Secrets            []string               `json:"secrets" yaml:"secrets"`
+	Values             []string               `json:"values" yaml:"values"`
+	Cookies            []dto.BrowserCookie    `json:"cookies" yaml:"cookies"`
+	OutDir             string                 `json:"outDir" yaml:"outDir"`
+	Tags               []string               `json:"tags" yaml:"tags"`                             // session tags in key=value format
+	MaxProgramBytes    int                    `json:"maxProgramBytes" yaml:"maxProgramBytes"`       // max size of a single program (default: 1MiB)
+	ScreenshotDelivery string                 `json:"screenshotDelivery" yaml:"screenshotDelivery"` // dto.ScreenshotDeliveryInline (default) or dto.ScreenshotDeliveryURL
+	MaxAttempts        *int                   `json:"maxAttempts" yaml:"maxAttempts"`               // max attempts of the backend to fetch/process a page (nil: backend default)
+	RecordWebSockets   bool                   `json:"recordWebSockets" yaml:"recordWebSockets"`     // record WebSocket frames from the session start (see GetWebSocketMessages)
+	NetworkThrottle    *dto.NetworkConditions `json:"networkThrottle" yaml:"networkThrottle"`       // network conditions emulated from the session start (e.g. dto.Slow3G)
+	InitScripts        []string               `json:"initScripts" yaml:"initScripts"`               // scripts run before page scripts on every navigation from the session start (see AddInitScript)
+	Locale             string                 `json:"locale" yaml:"locale"`                         // browser locale from the session start (e.g. de-DE), see SetLocale
+	Languages          []string               `json:"languages" yaml:"languages"`                   // preferred languages from the session start (default: [Locale])
+	GrantPermissions   []string               `json:"grantPermissions" yaml:"grantPermissions"`     // permissions granted to every origin from the session start (e.g. geolocation)
+	ColorScheme        string                 `json:"colorScheme" yaml:"colorScheme"`               // emulated prefers-color-scheme from the session start (dto.ColorSchemeDark, etc.)
+	ReducedMotion      bool                   `json:"reducedMotion" yaml:"reducedMotion"`           // emulate prefers-reduced-motion: reduce from the session start
+	Stealth            bool                   `json:"stealth" yaml:"stealth"`                       // enable anti-detection evasions from the session start
+	StealthOptions     *dto.StealthOptions    `json:"stealthOptions" yaml:"stealthOptions"`         // granular control over evasions enabled by Stealth
+	BrowserExtra       map[string]any         `json:"browserExtra" yaml:"browserExtra"`             // arbitrary backend browser options merged into the session start request
+}
+
+// startBrowserOpts returns browser options of a new session configured by cfg
+func (cfg Config) startBrowserOpts() dto.BrowserOpts {
+	return dto.BrowserOpts{
+		Headful:          cfg.LocalDebug,
+		ReturnScreenshot: lo.ToPtr(true),
+		Timeout:          cfg.Timeout,
+		RecordWebSockets: lo.Ternary(cfg.RecordWebSockets, lo.ToPtr(true), nil),
+		NetworkThrottle:  cfg.NetworkThrottle,
+		InitScripts:      cfg.InitScripts,
+		Locale:           cfg.Locale,
+		Languages:        cfg.Languages,
+		GrantPermissions: cfg.GrantPermissions,
+		ColorScheme:      cfg.ColorScheme,
+		ReducedMotion:    lo.Ternary(cfg.ReducedMotion, lo.ToPtr(true), nil),
+		Stealth:          lo.Ternary(cfg.Stealth, lo.ToPtr(true), nil),
+		StealthOptions:   cfg.StealthOptions,
+		Extra:            cfg.BrowserExtra,
+	}
 }
 
+// DefaultMaxProgramBytes is max size of a single program unless Config.MaxProgramBytes is set
+const DefaultMaxProgramBytes = 1 << 20
+
 type Option func(p *program)
 
 func WithSecrets(secrets map[string]string) Option {
@@ -141,8 +312,94 @@ func WithValues(values map[string]string) Option {
 	}
 }
 
+// WithScreenshotOnError makes every failed command take a best-effort screenshot with the given name
+// and attach it to the returned *BaasError
+func WithScreenshotOnError(name string) Option {
+	return func(p *program) {
+		p.screenshotOnError = name
+	}
+}
+
+// WithRetryStale makes commands failing because of a stale (detached) element to be retried,
+// so that the selector is resolved again
+func WithRetryStale() Option {
+	return func(p *program) {
+		p.retryStale = true
+	}
+}
+
+// WithCookieTracking makes program snapshot cookies after each command to detect their changes
+// (see Program.CookieChanges), it costs an additional round-trip per command
+func WithCookieTracking() Option {
+	return func(p *program) {
+		p.trackCookies = true
+	}
+}
+
+// WithReportScreenshots makes program pass screenshots returned by any command to the reporter
+// if it implements ScreenshotReporter
+func WithReportScreenshots() Option {
+	return func(p *program) {
+		p.reportScreenshots = true
+	}
+}
+
+// WithStepScreenshots makes program take a screenshot after every command and save it to dir
+// (as step-001.png, step-002.png, etc.), it costs an additional round-trip per command;
+// dir is created if missing, step screenshots are disabled (with a single error report) if they can't be saved
+func WithStepScreenshots(dir string) Option {
+	return func(p *program) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			p.report(ReportLevelError, fmt.Sprintf("Failed to create dir %s, step screenshots are disabled: %v", dir, err))
+			return
+		}
+		p.stepScreenshotsDir = dir
+	}
+}
+
+// WithDefaultActionOptions sets options applied to every command before the options passed to the method,
+// so per-call options override defaults with the same key (e.g. WithTimeout or WithoutTimeout override default WithTimeout)
+func WithDefaultActionOptions(opts ...ActionOption) Option {
+	return func(p *program) {
+		p.defaultActionOptions = append(p.defaultActionOptions, opts...)
+	}
+}
+
+// WithLogWriter makes program write log entries returned by every command to w as they arrive
+// (prefixed with timestamp and command), e.g. to tail logs of a long session from a file
+func WithLogWriter(w io.Writer) Option {
+	return func(p *program) {
+		p.logWriter = w
+	}
+}
+
+// WithAutoRestart makes program start a new session when the backend reports the session has expired
+// (e.g. long flows outliving it) and retry the failed command once, cookies last seen via GetCookies
+// (or cookie tracking) are carried over, other browser state (open page, local storage) is lost
+func WithAutoRestart() Option {
+	return func(p *program) {
+		p.autoRestart = true
+	}
+}
+
+// WithKeepAlive makes program send a cheap command (getURL) whenever the session was idle for interval
+// to prevent it from being reaped by the backend for inactivity (e.g. during human pauses),
+// pings are not counted in Info
+func WithKeepAlive(interval time.Duration) Option {
+	return func(p *program) {
+		p.keepAliveInterval = interval
+	}
+}
+
 func NewProgram(ctx context.Context, cfg Config, reporter Reporter, opts ...Option) (Program, error) {
-	client := NewClient(cfg.Url, cfg.ApiKey, time.Second*30)
+	p, err := newProgram(ctx, NewClient(cfg.Url, cfg.ApiKey, DefaultConnectTimeout, DefaultResponseTimeout), cfg, reporter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newProgram(ctx context.Context, client Client, cfg Config, reporter Reporter, opts ...Option) (*program, error) {
 	ctx, cancel := context.WithCancel(ctx)
 
 	p := &program{
@@ -156,18 +413,13 @@ func NewProgram(ctx context.Context, cfg Config, reporter Reporter, opts ...Opti
 	for _, opt := range opts {
 		opt(p)
 	}
+	if err := p.compileResponseCaptures(); err != nil {
+		cancel()
+		return nil, err
+	}
 
 	go func() {
-		defer cancel()
-		res, wait, err := client.RunAsync(ctx, dto.Config{
-			Browser: dto.BrowserOpts{
-				Headful:          cfg.LocalDebug,
-				ReturnScreenshot: lo.ToPtr(true),
-				Timeout:          cfg.Timeout,
-				Cookies:          cfg.Cookies,
-			},
-			UseRandomProxy: lo.ToPtr(cfg.UseProxy),
-		})
+		res, wait, err := client.RunAsync(ctx, p.sessionConfig(cfg.Cookies))
 		if err != nil {
 			p.exitWithError(err)
 			return
@@ -176,25 +428,89 @@ func NewProgram(ctx context.Context, cfg Config, reporter Reporter, opts ...Opti
 			p.exitWithError(errors.Errorf("%s", res.Error))
 			return
 		}
+		p.statsMu.Lock()
+		p.usedProxy = res.UsedProxy
 		p.sessionID = res.SessionID
-		wait()
+		p.statsMu.Unlock()
+		if p.keepAliveInterval > 0 {
+			go p.keepAlive()
+		}
+		p.waitSession(res.SessionID, wait)
 	}()
 
 	// wait until ready
-	for p.sessionID == "" {
+	for p.currentSessionID() == "" {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			reporter.Report("Waiting for sessionID...")
+			p.report(ReportLevelDebug, "Waiting for sessionID...")
 			time.Sleep(200 * time.Millisecond)
 		}
 	}
-	reporter.Report("Got sessionID: " + p.sessionID)
+	p.report(ReportLevelInfo, "Got sessionID: "+p.currentSessionID())
 
 	return p, nil
 }
 
+func (p *program) sessionConfig(cookies []dto.BrowserCookie) dto.Config {
+	browser := p.cfg.startBrowserOpts()
+	browser.Cookies = cookies
+	browser.ScreenshotDelivery = p.cfg.ScreenshotDelivery
+	browser.CaptureResponses = p.responseCapturePatterns()
+	return dto.Config{
+		Browser:        browser,
+		UseRandomProxy: lo.ToPtr(p.cfg.UseProxy),
+		MaxAttempts:    p.cfg.MaxAttempts,
+		SessionTags:    util.SliceToMap(p.cfg.Tags),
+	}
+}
+
+// waitSession blocks until the session ends, which finishes the program unless it restarts expired sessions
+func (p *program) waitSession(sessionID string, wait WaitFunc) {
+	err := wait(p.ctx, WithCloseOnCancel())
+	if p.autoRestart {
+		if err != nil {
+			p.report(ReportLevelError, fmt.Sprintf("Session %s ended: %v", sessionID, err))
+		}
+		return
+	}
+	if err != nil {
+		p.exitWithError(err)
+	}
+	p.cancel()
+}
+
+// restartSession replaces expired session with a new one restoring cookies last seen in the session
+// (from GetCookies or cookie tracking), or the initial Config.Cookies if none were seen;
+// sendMu is held so that no command (e.g. keepalive ping) is sent while the session is swapped
+func (p *program) restartSession() error {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	cookies := lo.Ternary(p.lastCookies != nil, p.lastCookies, p.cfg.Cookies)
+	res, wait, err := p.client.RunAsync(p.ctx, p.sessionConfig(cookies))
+	if err != nil {
+		return errors.Wrapf(err, "failed to restart session")
+	}
+	if res.Error != "" {
+		return errors.Errorf("failed to restart session: %s", res.Error)
+	}
+	p.report(ReportLevelInfo, fmt.Sprintf("Session %s expired, restarted as %s with %d cookies", p.sessionID, res.SessionID, len(cookies)))
+	p.statsMu.Lock()
+	p.usedProxy = res.UsedProxy
+	p.sessionID = res.SessionID
+	p.statsMu.Unlock()
+	go p.waitSession(res.SessionID, wait)
+	return nil
+}
+
+// currentSessionID returns sessionID for callers which hold neither sendMu nor statsMu
+func (p *program) currentSessionID() string {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	return p.sessionID
+}
+
 type program struct {
 	client    Client
 	err       error
@@ -205,8 +521,44 @@ type program struct {
 	secrets   map[string]string
 	values    map[string]string
 	cfg       Config
+
+	screenshotOnError string
+	retryStale        bool
+
+	statsMu      sync.Mutex // guards session stats read by Info, sessionID is also written under it
+	usedProxy    string
+	cost         float64
+	commandCount int
+
+	trackCookies  bool
+	cookies       []dto.BrowserCookie
+	cookieChanges []dto.CookieDelta
+
+	reportScreenshots bool
+	downloads         []dto.DownloadedFile
+
+	step               int
+	stepScreenshotsDir string
+	baselineDir        string
+
+	sendMu            sync.Mutex // held while a command is in flight
+	lastActivity      time.Time
+	keepAliveInterval time.Duration
+
+	defaultActionOptions []ActionOption
+
+	logWriter io.Writer
+
+	responseCaptures []responseCapture
+
+	reportLevel ReportLevel
+
+	autoRestart bool
+	lastCookies []dto.BrowserCookie
 }
 
+const staleRetryAttempts = 2
+
 func (p *program) Error() error {
 	return p.err
 }
@@ -218,8 +570,90 @@ func (p *program) exitWithError(err error) {
 	}
 }
 
+// runProgram sends program to the session, if the backend reports an error the failed response
+// is returned along with the error (e.g. to collect diagnostics)
 func (p *program) runProgram(prog string) (*dto.BrowserMessageOut, error) {
-	p.reporter.Report(fmt.Sprintf("Executing %q...", prog))
+	if p.currentSessionID() == "" {
+		return nil, errors.Wrapf(ErrNoActiveSession, "failed to execute %q", prog)
+	}
+	if maxBytes := lo.Ternary(p.cfg.MaxProgramBytes > 0, p.cfg.MaxProgramBytes, DefaultMaxProgramBytes); len(prog) > maxBytes {
+		return nil, errors.Wrapf(ErrProgramTooLarge, "program is %d bytes, max allowed is %d", len(prog), maxBytes)
+	}
+	res, err := p.sendProgram(prog)
+	for attempt := 0; err != nil && p.retryStale && attempt < staleRetryAttempts && isStaleElementError(err); attempt++ {
+		p.report(ReportLevelInfo, fmt.Sprintf("Retrying %q after stale element error: %v", prog, err))
+		res, err = p.sendProgram(prog)
+	}
+	if err != nil && p.autoRestart && isSessionExpiredError(err) {
+		if restartErr := p.restartSession(); restartErr != nil {
+			p.report(ReportLevelError, fmt.Sprintf("Failed to restart session for %q: %v", prog, restartErr))
+		} else {
+			res, err = p.sendProgram(prog)
+		}
+	}
+	p.step++
+	if p.stepScreenshotsDir != "" {
+		p.saveStepScreenshot()
+	}
+	if err != nil {
+		return res, p.wrapError(err)
+	}
+	if p.trackCookies {
+		p.snapshotCookies(prog)
+	}
+	return res, nil
+}
+
+func (p *program) saveStepScreenshot() {
+	name := fmt.Sprintf("step-%03d", p.step)
+	res, err := p.sendProgram(p.functionCall1("takeScreenshot", name))
+	if err != nil {
+		p.report(ReportLevelError, fmt.Sprintf("Failed to take screenshot of step %d: %v", p.step, err))
+		return
+	}
+	screenshot, err := p.screenshotOf(res, name)
+	if err != nil {
+		p.report(ReportLevelError, fmt.Sprintf("Failed to take screenshot of step %d: %v", p.step, err))
+		return
+	}
+	fileName := filepath.Join(p.stepScreenshotsDir, name+".png")
+	if err := os.WriteFile(fileName, screenshot, 0o644); err != nil {
+		p.report(ReportLevelError, fmt.Sprintf("Failed to save screenshot of step %d to %s, step screenshots are disabled: %v", p.step, fileName, err))
+		p.stepScreenshotsDir = ""
+	}
+}
+
+func (p *program) wrapError(err error) error {
+	if p.screenshotOnError == "" {
+		return err
+	}
+	baasErr := &BaasError{Err: err}
+	res, screenshotErr := p.sendProgram(p.functionCall1("takeScreenshot", p.screenshotOnError))
+	if screenshotErr != nil {
+		p.report(ReportLevelError, fmt.Sprintf("Failed to take screenshot on error: %v", screenshotErr))
+		return baasErr
+	}
+	if baasErr.Screenshot, screenshotErr = p.screenshotOf(res, p.screenshotOnError); screenshotErr != nil {
+		p.report(ReportLevelError, fmt.Sprintf("Failed to take screenshot on error: %v", screenshotErr))
+	}
+	return baasErr
+}
+
+func (p *program) sendProgram(prog string) (*dto.BrowserMessageOut, error) {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	return p.sendProgramLocked(prog)
+}
+
+func (p *program) sendProgramLocked(prog string) (*dto.BrowserMessageOut, error) {
+	return p.sendLocked(prog, true)
+}
+
+// sendLocked sends program to the session, unaccounted programs (keepalive pings) are not counted in Info
+func (p *program) sendLocked(prog string, accounted bool) (*dto.BrowserMessageOut, error) {
+	defer func() { p.lastActivity = time.Now() }()
+	p.report(ReportLevelDebug, fmt.Sprintf("Executing %q...", prog))
+	started := time.Now()
 	res, err := p.client.Message(p.ctx, dto.BrowserMessageIn{
 		SessionID: p.sessionID,
 		Program:   prog,
@@ -227,18 +661,102 @@ func (p *program) runProgram(prog string) (*dto.BrowserMessageOut, error) {
 		Values:    p.values,
 		Timeout:   p.cfg.MessageTimeout,
 	})
-	p.reporter.Report(fmt.Sprintf("Got result: %v (%s), %v", lo.FromPtr(res).Value, lo.FromPtr(res).Error, err))
+	if level := resultReportLevel(res, err); level == ReportLevelError {
+		// "Executing" line is not reported at error level, so the failed command is included
+		p.report(level, fmt.Sprintf("Got result of %q: %v (%s), %v", truncateReported(prog), lo.FromPtr(res).Value, lo.FromPtr(res).Error, err))
+	} else {
+		p.report(level, fmt.Sprintf("Got result: %v (%s), %v", lo.FromPtr(res).Value, lo.FromPtr(res).Error, err))
+	}
+	reportCommandEvent(p.reporter, prog, started, res, err)
 	if err != nil {
 		return nil, err
 	}
+	p.statsMu.Lock()
+	if accounted {
+		p.commandCount++
+		p.cost += res.Meta.Cost
+	}
+	if res.UsedProxy != "" {
+		p.usedProxy = res.UsedProxy
+	}
+	p.statsMu.Unlock()
+	p.reportScreenshotsOf(res)
+	p.writeLog(prog, res.Log)
+	p.saveResponses(res.Responses)
+	if len(res.DownloadedFile) > 0 {
+		p.downloads = append(p.downloads, dto.DownloadedFile{Name: res.DownloadedFileName, Data: res.DownloadedFile})
+	}
 	if res.Error != "" {
-		return nil, errors.Errorf("%s", res.Error)
+		return res, newCommandError(res.Error)
 	}
 	return res, nil
 }
 
+// runLlmProgram runs LLM action attaching screenshot and reasoning of the LLM returned with the failed response
+// (screenshot is only returned if the session was started with ReturnScreenshot)
+func (p *program) runLlmProgram(prog string) (*dto.BrowserMessageOut, error) {
+	res, err := p.runProgram(prog)
+	if err == nil || res == nil {
+		return res, err
+	}
+	llmErr := &LlmError{Err: err, Reasoning: res.Reasoning}
+	if names := lo.Keys(res.Screenshots); len(names) > 0 {
+		sort.Strings(names)
+		llmErr.Screenshot = res.Screenshots[names[0]]
+	}
+	return nil, llmErr
+}
+
+func (p *program) writeLog(prog string, log []string) {
+	if p.logWriter == nil {
+		return
+	}
+	timestamp := time.Now().Format(time.RFC3339)
+	for _, line := range log {
+		if _, err := fmt.Fprintf(p.logWriter, "%s [%s] %s\n", timestamp, prog, line); err != nil {
+			p.report(ReportLevelError, fmt.Sprintf("Failed to write log: %v", err))
+			return
+		}
+	}
+}
+
+// keepAlive pings the session until the program is finished, it skips pings while a command is in flight
+// or if the session was active within the interval
+func (p *program) keepAlive() {
+	ticker := time.NewTicker(p.keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !p.sendMu.TryLock() {
+			continue
+		}
+		if time.Since(p.lastActivity) >= p.keepAliveInterval {
+			if _, err := p.sendLocked(p.functionCall0("getURL"), false); err != nil {
+				p.report(ReportLevelError, fmt.Sprintf("Keepalive failed: %v", err))
+			}
+		}
+		p.sendMu.Unlock()
+	}
+}
+
+func (p *program) reportScreenshotsOf(res *dto.BrowserMessageOut) {
+	screenshotReporter, ok := p.reporter.(ScreenshotReporter)
+	if !p.reportScreenshots || !ok {
+		return
+	}
+	names := lo.Keys(res.Screenshots)
+	sort.Strings(names)
+	for _, name := range names {
+		screenshotReporter.Screenshot(name, res.Screenshots[name])
+	}
+}
+
 func (p *program) SetValueN(selector string, index int, value string, opts ...ActionOption) error {
-	_, err := p.runProgram(fmt.Sprintf("setValueN('%s', %d, '%s'%s)", selector, index, value, p.addArgs(opts)))
+	_, err := p.runProgram(fmt.Sprintf("setValueN('%s', %d, '%s'%s)", escapeJSString(selector), index, escapeJSString(value), p.addArgs(opts)))
 	if err != nil {
 		return err
 	}
@@ -246,7 +764,7 @@ func (p *program) SetValueN(selector string, index int, value string, opts ...Ac
 }
 
 func (p *program) GetElementValueN(selector string, index int, opts ...ActionOption) (string, error) {
-	res, err := p.runProgram(fmt.Sprintf("getElementValueN('%s', %d%s)", selector, index, p.addArgs(opts)))
+	res, err := p.runProgram(fmt.Sprintf("getElementValueN('%s', %d%s)", escapeJSString(selector), index, p.addArgs(opts)))
 	if err != nil {
 		return "", err
 	}
@@ -254,13 +772,31 @@ func (p *program) GetElementValueN(selector string, index int, opts ...ActionOpt
 }
 
 func (p *program) ClickN(selector string, index int, opts ...ActionOption) error {
-	_, err := p.runProgram(fmt.Sprintf("clickN('%s', %d%s)", selector, index, p.addArgs(opts)))
+	_, err := p.runProgram(fmt.Sprintf("clickN('%s', %d%s)", escapeJSString(selector), index, p.addArgs(opts)))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// ClickIfPresent clicks the element only if it is present (e.g. to dismiss cookie banner) in a single round-trip,
+// returns whether the element was clicked
+func (p *program) ClickIfPresent(selector string, opts ...ActionOption) (bool, error) {
+	res, err := p.runProgram(fmt.Sprintf(`
+			if (%s) {
+				%s
+				true
+			} else {
+				false
+			}`,
+		p.functionCall1("isElementPresent", selector, opts...),
+		p.functionCall1("click", selector, opts...)))
+	if err != nil {
+		return false, err
+	}
+	return valueToBool(res.Value)
+}
+
 func (p *program) Click(selector string, opts ...ActionOption) error {
 	_, err := p.runProgram(p.functionCall1("click", selector, opts...))
 	if err != nil {
@@ -269,6 +805,31 @@ func (p *program) Click(selector string, opts ...ActionOption) error {
 	return nil
 }
 
+// SetRange sets value of the range input dispatching input and change events
+func (p *program) SetRange(selector string, value float64, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("setRange('%s', %s%s)", escapeJSString(selector), strconv.FormatFloat(value, 'f', -1, 64), p.addArgs(opts)))
+	return err
+}
+
+const (
+	dateInputFormat     = "2006-01-02"
+	dateTimeInputFormat = "2006-01-02T15:04"
+)
+
+// SetDate sets value of the date input (formatted as yyyy-mm-dd regardless of the browser locale)
+// dispatching input and change events
+func (p *program) SetDate(selector string, t time.Time, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall2("setDate", selector, t.Format(dateInputFormat), opts...))
+	return err
+}
+
+// SetDateTime sets value of the datetime-local input (formatted as yyyy-mm-ddThh:mm in the time's location)
+// dispatching input and change events
+func (p *program) SetDateTime(selector string, t time.Time, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall2("setDateTime", selector, t.Format(dateTimeInputFormat), opts...))
+	return err
+}
+
 func (p *program) GetInnerText(selector string, opts ...ActionOption) (string, error) {
 	res, err := p.runProgram(p.functionCall1("getInnerText", selector, opts...))
 	if err != nil {
@@ -277,6 +838,19 @@ func (p *program) GetInnerText(selector string, opts ...ActionOption) (string, e
 	return res.Value.(string), nil
 }
 
+// WaitAndGetText waits until the element is visible and returns its inner text in a single round-trip
+func (p *program) WaitAndGetText(selector string, opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(fmt.Sprintf(`
+			%s
+			%s`,
+		p.functionCall1("waitVisible", selector, opts...),
+		p.functionCall1("getInnerText", selector, opts...)))
+	if err != nil {
+		return "", err
+	}
+	return valueToString(res.Value)
+}
+
 func (p *program) GetSecret(name string, opts ...ActionOption) (string, error) {
 	res, err := p.runProgram(p.functionCall1("getSecret", name, opts...))
 	if err != nil {
@@ -303,7 +877,7 @@ func (p *program) CountElements(selector string, opts ...ActionOption) (int, err
 	if err != nil {
 		return 0, err
 	}
-	return int(res.Value.(float64)), nil
+	return valueToInt(res.Value)
 }
 
 func (p *program) IsElementPresent(selector string, opts ...ActionOption) (bool, error) {
@@ -314,18 +888,44 @@ func (p *program) IsElementPresent(selector string, opts ...ActionOption) (bool,
 	return res.Value.(bool), nil
 }
 
+// IsElementPresentAnyFrame searches the element in the main frame and all child frames and returns
+// which frame matched (empty for the main frame, otherwise a selector of the frame usable with WithIframe)
+func (p *program) IsElementPresentAnyFrame(selector string, opts ...ActionOption) (bool, string, error) {
+	res, err := p.runProgram(p.functionCall1("isElementPresentAnyFrame", selector, opts...))
+	if err != nil {
+		return false, "", err
+	}
+	var result struct {
+		Present bool   `json:"present"`
+		Frame   string `json:"frame"`
+	}
+	if err := decodeValue(res.Value, &result); err != nil {
+		return false, "", err
+	}
+	return result.Present, result.Frame, nil
+}
+
+// IsLoading returns whether the main frame still has in-flight navigation or loading
+func (p *program) IsLoading(opts ...ActionOption) (bool, error) {
+	res, err := p.runProgram(p.functionCall0("isLoading", opts...))
+	if err != nil {
+		return false, err
+	}
+	return valueToBool(res.Value)
+}
+
 func (p *program) LlmClick(description string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall1("llmClick", description, opts...))
+	_, err := p.runLlmProgram(p.functionCall1("llmClick", description, opts...))
 	return err
 }
 
 func (p *program) LlmSendKeys(description, value string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall2("llmSendKeys", description, value, opts...))
+	_, err := p.runLlmProgram(p.functionCall2("llmSendKeys", description, value, opts...))
 	return err
 }
 
 func (p *program) LlmClickElement(elements []string, description string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall2("llmClickElement", strings.Join(elements, ","), description, opts...))
+	_, err := p.runLlmProgram(p.functionCall2("llmClickElement", strings.Join(elements, ","), description, opts...))
 	if err != nil {
 		return err
 	}
@@ -341,13 +941,30 @@ func (p *program) FindVisibleElements(elements []string, addAttributeName string
 }
 
 func (p *program) LlmText(description string, opts ...ActionOption) (string, error) {
-	res, err := p.runProgram(p.functionCall1("llmText", description, opts...))
+	res, err := p.runLlmProgram(p.functionCall1("llmText", description, opts...))
 	if err != nil {
 		return "", err
 	}
 	return res.Value.(string), nil
 }
 
+const llmReadImageScreenshot = "llm-read-image"
+
+// LlmReadImage takes screenshot of the element and asks multimodal LLM the question about it,
+// it is meant for content which is unreadable from the DOM (canvas, images); the screenshot stays in the session
+// (both steps run as a single program, so the image isn't transferred to the client)
+func (p *program) LlmReadImage(selector, question string, opts ...ActionOption) (string, error) {
+	res, err := p.runLlmProgram(fmt.Sprintf(`
+			%s
+			%s`,
+		p.functionCall1("takeScreenshot", llmReadImageScreenshot, append(slices.Clone(opts), WithSelector(selector))...),
+		p.functionCall2("llmReadImage", llmReadImageScreenshot, question, opts...)))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read image of %q", selector)
+	}
+	return valueToString(res.Value)
+}
+
 func (p *program) Log(message string, opts ...ActionOption) error {
 	_, err := p.runProgram(p.functionCall1("log", message, opts...))
 	return err
@@ -358,13 +975,74 @@ func (p *program) LogURL(opts ...ActionOption) error {
 	return err
 }
 
+// GetConsoleErrors returns error-level console messages accumulated since the last navigation
+func (p *program) GetConsoleErrors(opts ...ActionOption) ([]string, error) {
+	res, err := p.runProgram(p.functionCall0("getConsoleErrors", opts...))
+	if err != nil {
+		return nil, err
+	}
+	var consoleErrors []string
+	if err := decodeValue(res.Value, &consoleErrors); err != nil {
+		return nil, err
+	}
+	return consoleErrors, nil
+}
+
 func (p *program) ScrollToBottom(opts ...ActionOption) error {
 	_, err := p.runProgram(p.functionCall0("scrollToBottom", opts...))
 	return err
 }
 
+// ScrollElement scrolls the element's own scroll container (e.g. chat log or data grid) by x and y pixels
+func (p *program) ScrollElement(selector string, x, y int, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("scrollElement('%s', %d, %d%s)", escapeJSString(selector), x, y, p.addArgs(opts)))
+	return err
+}
+
+// ScrollTo scrolls the window to the absolute position
+func (p *program) ScrollTo(x, y int, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("scrollTo(%d, %d%s)", x, y, p.addArgs(opts)))
+	return err
+}
+
+// GetScrollPosition returns current scroll position of the window (fractional on high-DPI screens)
+func (p *program) GetScrollPosition(opts ...ActionOption) (x, y float64, err error) {
+	res, err := p.runProgram(p.functionCall0("getScrollPosition", opts...))
+	if err != nil {
+		return 0, 0, err
+	}
+	var position struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	}
+	if err := decodeValue(res.Value, &position); err != nil {
+		return 0, 0, err
+	}
+	return position.X, position.Y, nil
+}
+
 func (p *program) EvaluateJS(script string, opts ...ActionOption) (any, error) {
-	return p.runProgram(p.functionCall1("evaluateJS", script, opts...))
+	res, err := p.runProgram(p.functionCall1("evaluateJS", script, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Assert evaluates boolean JS expression on the page and returns error if it evaluates to false
+func (p *program) Assert(expression string, opts ...ActionOption) error {
+	res, err := p.runProgram(p.functionCall1("evaluateJS", expression, opts...))
+	if err != nil {
+		return err
+	}
+	ok, err := valueToBool(res.Value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to evaluate assertion %q", expression)
+	}
+	if !ok {
+		return errors.Errorf("assertion failed: %s", expression)
+	}
+	return nil
 }
 
 func (p *program) Reload(opts ...ActionOption) error {
@@ -372,8 +1050,19 @@ func (p *program) Reload(opts ...ActionOption) error {
 	return err
 }
 
+// ResetBrowserState clears cache, cookies, local and session storage of the browser,
+// it is a cheaper alternative to starting a fresh session when running independent flows
+func (p *program) ResetBrowserState(opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall0("resetBrowserState", opts...))
+	return err
+}
+
 func (p *program) Navigate(url string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall1("navigate", url, opts...))
+	url, opts, err := p.navigationTarget(url, opts)
+	if err != nil {
+		return err
+	}
+	_, err = p.runProgram(p.functionCall1("navigate", url, opts...))
 	return err
 }
 
@@ -385,6 +1074,26 @@ func (p *program) OuterHtml(selector string, opts ...ActionOption) (string, erro
 	return res.OutHTML, nil
 }
 
+// GetPageSource returns the original document source as it was delivered by the server (before any scripts ran),
+// unlike OuterHtml("html") which serializes the current (rendered) DOM
+func (p *program) GetPageSource(opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall0("getPageSource", opts...))
+	if err != nil {
+		return "", err
+	}
+	return valueToString(res.Value)
+}
+
+// GetNavigationBody returns raw body of the most recent top-level navigation response
+// (e.g. JSON returned by an API endpoint), the body is decoded to UTF-8 according to the response charset
+func (p *program) GetNavigationBody(opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall0("getNavigationBody", opts...))
+	if err != nil {
+		return "", err
+	}
+	return valueToString(res.Value)
+}
+
 func (p *program) InnerHtml(selector string, opts ...ActionOption) (string, error) {
 	res, err := p.runProgram(p.functionCall1("innerHtml", selector, opts...))
 	if err != nil {
@@ -393,8 +1102,53 @@ func (p *program) InnerHtml(selector string, opts ...ActionOption) (string, erro
 	return res.OutHTML, nil
 }
 
-func (p *program) ReplaceInnerHtml(selector, html string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall2("replaceInnerHtml", selector, html, opts...))
+func (p *program) GetAttribute(selector, name string, opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall2("getAttribute", selector, name, opts...))
+	if err != nil {
+		return "", err
+	}
+	if res.Value == nil {
+		return "", nil
+	}
+	return valueToString(res.Value)
+}
+
+// AssertAttribute checks that attribute of the element equals expected value
+// (or matches it if WithRegexMatch is passed)
+func (p *program) AssertAttribute(selector, name, expected string, opts ...ActionOption) error {
+	_, regexMatch, opts := p.takeClientArg(regexMatchArg, opts)
+	var re *regexp.Regexp
+	if regexMatch {
+		var err error
+		if re, err = regexp.Compile(expected); err != nil {
+			return errors.Wrapf(err, "invalid expected pattern %q", expected)
+		}
+	}
+	actual, err := p.GetAttribute(selector, name, opts...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get attribute %q of %q", name, selector)
+	}
+	if re != nil && !re.MatchString(actual) {
+		return errors.Errorf("attribute %q of %q is %q, expected to match %q", name, selector, actual, expected)
+	}
+	if re == nil && actual != expected {
+		return errors.Errorf("attribute %q of %q is %q, expected %q", name, selector, actual, expected)
+	}
+	return nil
+}
+
+// AssertGoneFor checks that the element stays absent for the whole duration (e.g. an error banner never appears);
+// unlike waiting for an element to disappear it doesn't return early and fails as soon as the element shows up
+func (p *program) AssertGoneFor(selector string, duration string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall2("assertGoneFor", selector, duration, opts...))
+	if isElementAppearedError(err) {
+		return errors.Wrapf(err, "%q did not stay absent for %s", selector, duration)
+	}
+	return err
+}
+
+func (p *program) ReplaceInnerHtml(selector, html string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall2("replaceInnerHtml", selector, html, opts...))
 	return err
 }
 
@@ -403,6 +1157,12 @@ func (p *program) SendKeys(text string, opts ...ActionOption) error {
 	return err
 }
 
+// Paste puts text to the clipboard and fires paste event on the element (for inputs which handle paste differently from typing)
+func (p *program) Paste(selector, text string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall2("paste", selector, text, opts...))
+	return err
+}
+
 func (p *program) Sleep(duration string, opts ...ActionOption) error {
 	_, err := p.runProgram(p.functionCall1("sleep", duration, opts...))
 	return err
@@ -437,6 +1197,18 @@ func (p *program) WaitFileDownload(duration string, opts ...ActionOption) (bool,
 	return res.Value.(bool), nil
 }
 
+// GetPendingDownloadName returns name of the file being downloaded (or empty string if there is no pending download)
+func (p *program) GetPendingDownloadName(opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall0("getPendingDownloadName", opts...))
+	if err != nil {
+		return "", err
+	}
+	if res.Value == nil {
+		return "", nil
+	}
+	return valueToString(res.Value)
+}
+
 func (p *program) ExecuteAndDownloadFile(program string, fileName string, waitStarted, waitDownloaded string, opts ...ActionOption) ([]byte, error) {
 	res, err := p.runProgram(fmt.Sprintf(`
 			%s
@@ -457,12 +1229,12 @@ func (p *program) ExecuteAndDownloadFile(program string, fileName string, waitSt
 	var message string
 	if err := os.WriteFile(fileName, res.DownloadedFile, 0o644); err != nil {
 		message = fmt.Sprintf("failed to save file %s: %q", fileName, err.Error())
-		p.reporter.Report(message)
+		p.report(ReportLevelError, message)
 		return nil, err
 	} else {
 		message = fmt.Sprintf("%q saved to ", fileName) +
 			termlink.ColorLink(fileName, fmt.Sprintf("file://%s", fileName), "italic green")
-		p.reporter.Report(message)
+		p.report(ReportLevelInfo, message)
 	}
 	return res.DownloadedFile, nil
 }
@@ -486,16 +1258,168 @@ func (p *program) WaitVisible(selector string, opts ...ActionOption) error {
 	return err
 }
 
+// WaitForStable waits until the element's bounding box hasn't changed for quietMs milliseconds
+// (useful to wait for CSS transitions and animations to finish before interacting with the element)
+func (p *program) WaitForStable(selector string, quietMs int, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("waitForStable('%s', %d%s)", escapeJSString(selector), quietMs, p.addArgs(opts)))
+	return err
+}
+
+// WaitForFonts waits until web fonts of the document are loaded (document.fonts.ready),
+// a cheap way to stabilize screenshots that would otherwise show fallback fonts
+func (p *program) WaitForFonts(timeout string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall1("waitForFonts", timeout, opts...))
+	if err != nil {
+		return errors.Wrapf(err, "fonts are not loaded within %s", timeout)
+	}
+	return nil
+}
+
+// WaitForTextStable waits until the element's text hasn't changed for quietMs milliseconds
+// (e.g. counters and live values settling) and returns the settled text
+func (p *program) WaitForTextStable(selector string, quietMs int, opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(fmt.Sprintf("waitForTextStable('%s', %d%s)", escapeJSString(selector), quietMs, p.addArgs(opts)))
+	if err != nil {
+		return "", err
+	}
+	return valueToString(res.Value)
+}
+
+// WaitForTextMatch waits until text of the element matches the regular expression (e.g. to wait for prices or counts),
+// pattern must be compatible with both Go and JavaScript regular expressions
+func (p *program) WaitForTextMatch(selector, pattern string, opts ...ActionOption) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return errors.Wrapf(err, "invalid pattern %q", pattern)
+	}
+	_, err := p.runProgram(p.functionCall2("waitForTextMatch", selector, pattern, opts...))
+	return err
+}
+
+// WaitForPopup waits until a new window (e.g. OAuth popup) is opened and returns its tab ID to use with SwitchTab
+func (p *program) WaitForPopup(timeout string, opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall1("waitForPopup", timeout, opts...))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to wait for popup window within %s", timeout)
+	}
+	if res.Value == nil {
+		return "", errors.Errorf("no popup window opened within %s", timeout)
+	}
+	tabID, err := valueToString(res.Value)
+	if err != nil {
+		return "", err
+	}
+	if tabID == "" {
+		return "", errors.Errorf("no popup window opened within %s", timeout)
+	}
+	return tabID, nil
+}
+
+// SwitchTab makes the tab with tabID current, so that following commands are executed there
+func (p *program) SwitchTab(tabID string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall1("switchTab", tabID, opts...))
+	return err
+}
+
+// WaitForAny waits until any of the selectors matches an element and returns the selector which matched first
+func (p *program) WaitForAny(selectors []string, opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(fmt.Sprintf("waitForAny(%s%s)", jsStringArray(selectors), p.addArgs(opts)))
+	if err != nil {
+		return "", err
+	}
+	return valueToString(res.Value)
+}
+
+// WaitForManualIntervention pauses the flow while the element matching promptSelector (e.g. CAPTCHA) is present
+// until it is resolved manually in the headful (local debug) browser or timeout expires.
+// Returns ErrManualInterventionRequired if the prompt is present whilst the browser runs headless
+func (p *program) WaitForManualIntervention(promptSelector string, timeout string, opts ...ActionOption) error {
+	present, err := p.IsElementPresent(promptSelector, opts...)
+	if err != nil {
+		return err
+	}
+	if !present {
+		return nil
+	}
+	if !p.cfg.LocalDebug {
+		return errors.Wrapf(ErrManualInterventionRequired, "prompt %q is present", promptSelector)
+	}
+	p.report(ReportLevelInfo, fmt.Sprintf("Waiting for manual intervention (%q is present) for %s...", promptSelector, timeout))
+	_, err = p.runProgram(p.functionCall2("waitForManualIntervention", promptSelector, timeout, opts...))
+	return err
+}
+
+// WaitForCookie waits until cookie with the name is set (e.g. session cookie after login) and returns it
+func (p *program) WaitForCookie(name string, timeout string, opts ...ActionOption) (dto.BrowserCookie, error) {
+	var cookie dto.BrowserCookie
+	res, err := p.runProgram(p.functionCall2("waitForCookie", name, timeout, opts...))
+	if err != nil {
+		return cookie, err
+	}
+	if err := decodeValue(res.Value, &cookie); err != nil {
+		return cookie, err
+	}
+	return cookie, nil
+}
+
 func (p *program) NavigateStatus(url string, opts ...ActionOption) (int, error) {
+	url, opts, err := p.navigationTarget(url, opts)
+	if err != nil {
+		return 0, err
+	}
 	res, err := p.runProgram(p.functionCall1("navigateStatus", url, opts...))
 	if err != nil {
 		return 0, err
 	}
-	status, ok := res.Value.(float64)
-	if !ok {
-		return 0, errors.Errorf("Failed to convert status code to int %v", res.Value)
+	status, err := valueToInt(res.Value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to convert status code to int")
+	}
+	return status, nil
+}
+
+// NavigateResult navigates to url and returns final URL, status and redirect chain of the navigation
+func (p *program) NavigateResult(url string, opts ...ActionOption) (dto.NavigationResult, error) {
+	var result dto.NavigationResult
+	url, opts, err := p.navigationTarget(url, opts)
+	if err != nil {
+		return result, err
+	}
+	res, err := p.runProgram(p.functionCall1("navigateResult", url, opts...))
+	if err != nil {
+		return result, err
+	}
+	if err := decodeValue(res.Value, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// GetRedirectChain navigates to url and returns every hop of the navigation (including the final one)
+// recorded from network events
+func (p *program) GetRedirectChain(url string, opts ...ActionOption) ([]dto.RedirectHop, error) {
+	url, opts, err := p.navigationTarget(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.runProgram(p.functionCall1("getRedirectChain", url, opts...))
+	if err != nil {
+		return nil, err
+	}
+	var chain []dto.RedirectHop
+	if err := decodeValue(res.Value, &chain); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// LastNavigationStatus returns status code of the most recent main frame navigation
+// (e.g. caused by Submit or client-side actions) without navigating again
+func (p *program) LastNavigationStatus(opts ...ActionOption) (int, error) {
+	res, err := p.runProgram(p.functionCall0("lastNavigationStatus", opts...))
+	if err != nil {
+		return 0, err
 	}
-	return int(status), nil
+	return valueToInt(res.Value)
 }
 
 func (p *program) TakeScreenshot(name string, opts ...ActionOption) ([]byte, error) {
@@ -503,32 +1427,75 @@ func (p *program) TakeScreenshot(name string, opts ...ActionOption) ([]byte, err
 	if err != nil {
 		return nil, err
 	}
-	if len(res.Screenshots[name]) == 0 {
-		return nil, errors.Errorf("screenshot with name %s wasn't returned", name)
+	return p.screenshotOf(res, name)
+}
+
+// screenshotOf returns screenshot returned inline or fetches it if it was delivered by URL
+func (p *program) screenshotOf(res *dto.BrowserMessageOut, name string) ([]byte, error) {
+	if len(res.Screenshots[name]) > 0 {
+		return res.Screenshots[name], nil
+	}
+	if url := res.ScreenshotURLs[name]; url != "" {
+		return p.client.FetchScreenshot(p.ctx, url)
 	}
-	return res.Screenshots[name], nil
+	return nil, errors.Errorf("screenshot with name %s wasn't returned", name)
 }
 
+// SaveScreenshot takes screenshot and writes it to fileName, which may contain {session}, {timestamp} and {step} tokens
+// substituted with session ID, current time (e.g. 20060102-150405) and the number of commands run so far including
+// the screenshot; {step} counts every command sent to the session rather than user actions, so compound methods
+// (e.g. Paginate, which polls the page while waiting) advance it by more than one, as in WithStepScreenshots
 func (p *program) SaveScreenshot(name string, fileName string, opts ...ActionOption) error {
 	screenshot, err := p.TakeScreenshot(name, opts...)
 	if err != nil {
 		return err
 	}
+	fileName = p.expandFileName(fileName, time.Now())
 	var message string
 	if err := os.WriteFile(fileName, screenshot, 0o644); err != nil {
 		message = fmt.Sprintf("failed to save %q to %s: %q", name, fileName, err.Error())
-		p.reporter.Report(message)
+		p.report(ReportLevelError, message)
 		return err
 	} else {
 		message = fmt.Sprintf("%q saved to ", name) +
 			termlink.ColorLink(name, fmt.Sprintf("file://%s", fileName), "italic green")
-		p.reporter.Report(message)
+		p.report(ReportLevelInfo, message)
+	}
+	return nil
+}
+
+// SaveSnapshot saves the page as a self-contained document (MHTML with inlined CSS and images) renderable offline,
+// fileName may contain the same tokens as SaveScreenshot. Snapshots of image-heavy pages may take tens of megabytes
+// and are returned in a single response, so they may need a longer WithTimeout
+func (p *program) SaveSnapshot(fileName string, opts ...ActionOption) error {
+	res, err := p.runProgram(p.functionCall0("saveSnapshot", opts...))
+	if err != nil {
+		return err
+	}
+	snapshot, err := valueToString(res.Value)
+	if err != nil {
+		return err
+	}
+	fileName = p.expandFileName(fileName, time.Now())
+	if err := os.WriteFile(fileName, []byte(snapshot), 0o644); err != nil {
+		return errors.Wrapf(err, "failed to save snapshot to %s", fileName)
 	}
+	p.report(ReportLevelInfo, fmt.Sprintf("snapshot (%d bytes) saved to %s", len(snapshot), fileName))
 	return nil
 }
 
+const fileNameTimestampFormat = "20060102-150405"
+
+func (p *program) expandFileName(fileName string, now time.Time) string {
+	return strings.NewReplacer(
+		"{session}", p.currentSessionID(),
+		"{timestamp}", now.Format(fileNameTimestampFormat),
+		"{step}", fmt.Sprintf("%03d", p.step),
+	).Replace(fileName)
+}
+
 func (p *program) LlmSetValue(desc, value string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall2("llmSetValue", desc, value, opts...))
+	_, err := p.runLlmProgram(p.functionCall2("llmSetValue", desc, value, opts...))
 	if err != nil {
 		return err
 	}
@@ -536,7 +1503,7 @@ func (p *program) LlmSetValue(desc, value string, opts ...ActionOption) error {
 }
 
 func (p *program) LlmSetValueSkipVerify(desc, value string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall2("llmSetValueSkipVerify", desc, value, opts...))
+	_, err := p.runLlmProgram(p.functionCall2("llmSetValueSkipVerify", desc, value, opts...))
 	if err != nil {
 		return err
 	}
@@ -544,14 +1511,33 @@ func (p *program) LlmSetValueSkipVerify(desc, value string, opts ...ActionOption
 }
 
 func (p *program) LlmLogin(username, password string, opts ...ActionOption) error {
-	_, err := p.runProgram(p.functionCall2("llmLogin", username, password, opts...))
+	_, err := p.runLlmProgram(p.functionCall2("llmLogin", username, password, opts...))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// LlmIsLoginPage returns whether the current page is a login wall rather than the target content
+// (e.g. to run LlmLogin only when needed)
+func (p *program) LlmIsLoginPage(opts ...ActionOption) (bool, error) {
+	res, err := p.runLlmProgram(p.functionCall0("llmIsLoginPage", opts...))
+	if err != nil {
+		return false, err
+	}
+	return valueToBool(res.Value)
+}
+
+// Execute runs program and returns its result decoded from JSON as is
+// (i.e. all numbers are returned as json.Number, objects as map[string]any and arrays as []any),
+// use typed ExecuteXXX methods to get the result converted to the expected type;
+// options are appended to the arguments of the function call the program ends with
+// (e.g. getInnerText('h1') with WithTimeout("5s") runs getInnerText('h1', 'timeout:5s'))
 func (p *program) Execute(program string, opts ...ActionOption) (any, error) {
+	program, err := p.appendArgs(program, opts)
+	if err != nil {
+		return "", err
+	}
 	res, err := p.runProgram(program)
 	if err != nil {
 		return "", err
@@ -559,6 +1545,334 @@ func (p *program) Execute(program string, opts ...ActionOption) (any, error) {
 	return res.Value, nil
 }
 
+// ExecuteInFrame runs program within the context of the frameIndex-th frame of the page (in document order),
+// it is meant for frames which can't be targeted by WithIframe (e.g. anonymous frames), cross-origin frames
+// are only accessible if the backend runs them in-process (site isolation disabled), otherwise the command fails
+func (p *program) ExecuteInFrame(frameIndex int, program string, opts ...ActionOption) (any, error) {
+	if frameIndex < 0 {
+		return nil, errors.Errorf("invalid frame index %d: must be non-negative", frameIndex)
+	}
+	res, err := p.runProgram(fmt.Sprintf("executeInFrame(%d, '%s'%s)", frameIndex, escapeJSString(program), p.addArgs(opts)))
+	if err != nil {
+		return nil, err
+	}
+	return res.Value, nil
+}
+
+// ForEachURL navigates to each of urls one by one (the session has a single browser) and runs program there,
+// failures are captured into the corresponding result without stopping processing of the rest of urls;
+// navigateOpts apply to the navigation (e.g. WithEncodeURL), opts to the program (see Execute)
+func (p *program) ForEachURL(urls []string, program string, navigateOpts []ActionOption, opts ...ActionOption) ([]dto.URLResult, error) {
+	results := make([]dto.URLResult, 0, len(urls))
+	for _, url := range urls {
+		if err := p.ctx.Err(); err != nil {
+			return results, err
+		}
+		result := dto.URLResult{URL: url}
+		if err := p.Navigate(url, navigateOpts...); err != nil {
+			result.Error = err.Error()
+		} else if value, err := p.Execute(program, opts...); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Value = value
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+const (
+	pageLoadPollInterval = 200 * time.Millisecond
+	pageLoadTimeout      = 30 * time.Second
+)
+
+// pageSignatureScript hashes URL and text of the page to detect that the content was replaced
+// (both by navigation and by client-side rendering)
+const pageSignatureScript = `(() => { const s = location.href + (document.body ? document.body.innerText : ''); let h = 0; for (let i = 0; i < s.length; i++) h = (h * 31 + s.charCodeAt(i)) | 0; return String(h) })()`
+
+// Paginate runs extract on the current page, clicks nextSelector and waits for the page content to change and load
+// until there is no next element or maxPages (if positive) pages were processed, returns amount of processed pages;
+// WithTimeout limits waiting for each page (30s by default)
+func (p *program) Paginate(nextSelector string, maxPages int, extract func(p Program) error, opts ...ActionOption) (int, error) {
+	pages := 0
+	for {
+		if err := extract(p); err != nil {
+			return pages, errors.Wrapf(err, "failed to extract page %d", pages+1)
+		}
+		pages++
+		if maxPages > 0 && pages >= maxPages {
+			return pages, nil
+		}
+		hasNext, err := p.IsElementPresent(nextSelector, opts...)
+		if err != nil {
+			return pages, err
+		}
+		if !hasNext {
+			return pages, nil
+		}
+		signature, err := p.pageSignature(opts...)
+		if err != nil {
+			return pages, err
+		}
+		if err := p.Click(nextSelector, opts...); err != nil {
+			return pages, errors.Wrapf(err, "failed to go to page %d", pages+1)
+		}
+		if err := p.waitLoaded(signature, opts...); err != nil {
+			return pages, errors.Wrapf(err, "failed to load page %d", pages+1)
+		}
+	}
+}
+
+func (p *program) pageSignature(opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall1("evaluateJS", pageSignatureScript, opts...))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get page signature")
+	}
+	return valueToString(res.Value)
+}
+
+// waitLoaded polls until the page signature differs from the one before navigation and the page has finished loading
+func (p *program) waitLoaded(previousSignature string, opts ...ActionOption) error {
+	timeout := pageLoadTimeout
+	if value, found, _ := p.takeClientArg("timeout", opts); found {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			timeout = parsed
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		signature, err := p.pageSignature(opts...)
+		if err != nil {
+			return err
+		}
+		if signature != previousSignature {
+			loading, err := p.IsLoading(opts...)
+			if err != nil || !loading {
+				return err
+			}
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("page was not replaced after %s", timeout)
+		}
+		select {
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		case <-time.After(pageLoadPollInterval):
+		}
+	}
+}
+
+func (p *program) ExecuteString(program string, opts ...ActionOption) (string, error) {
+	value, err := p.Execute(program, opts...)
+	if err != nil {
+		return "", err
+	}
+	return valueToString(value)
+}
+
+func (p *program) ExecuteInt(program string, opts ...ActionOption) (int, error) {
+	value, err := p.Execute(program, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return valueToInt(value)
+}
+
+func (p *program) ExecuteFloat(program string, opts ...ActionOption) (float64, error) {
+	value, err := p.Execute(program, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return valueToFloat(value)
+}
+
+func (p *program) ExecuteBool(program string, opts ...ActionOption) (bool, error) {
+	value, err := p.Execute(program, opts...)
+	if err != nil {
+		return false, err
+	}
+	return valueToBool(value)
+}
+
+// ExecuteJSON runs program and unmarshals its result into v (which must be a pointer)
+func (p *program) ExecuteJSON(program string, v any, opts ...ActionOption) error {
+	value, err := p.Execute(program, opts...)
+	if err != nil {
+		return err
+	}
+	return decodeValue(value, v)
+}
+
+// SetNetworkConditions throttles the network of the browser (e.g. to emulate slow connection)
+func (p *program) SetNetworkConditions(downloadKbps, uploadKbps int, latencyMs int, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("setNetworkConditions(%d, %d, %d%s)", downloadKbps, uploadKbps, latencyMs, p.addArgs(opts)))
+	return err
+}
+
+// SetOffline disconnects (or reconnects) the browser from the network keeping the configured network conditions
+func (p *program) SetOffline(offline bool, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("setOffline(%t%s)", offline, p.addArgs(opts)))
+	return err
+}
+
+// SetWindowBounds moves and resizes the browser window (headful mode), unlike the render viewport
+// (e.g. BrowserOpts.Width/Height) it includes browser UI such as tab strip and address bar
+func (p *program) SetWindowBounds(x, y, width, height int, opts ...ActionOption) error {
+	if width <= 0 || height <= 0 {
+		return errors.Errorf("window size must be positive, got %dx%d", width, height)
+	}
+	_, err := p.runProgram(fmt.Sprintf("setWindowBounds(%d, %d, %d, %d%s)", x, y, width, height, p.addArgs(opts)))
+	return err
+}
+
+// SetLocale changes locale of the browser updating both Accept-Language header and JS locale (navigator.language, Intl)
+func (p *program) SetLocale(locale string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall1("setLocale", locale, opts...))
+	return err
+}
+
+// SetPermission sets state of the permission (e.g. geolocation, notifications, camera) for the origin
+// to dto.PermissionGranted, dto.PermissionDenied or dto.PermissionPrompt, so that flows don't get blocked by permission prompts
+func (p *program) SetPermission(origin, name, state string, opts ...ActionOption) error {
+	if !lo.Contains([]string{dto.PermissionGranted, dto.PermissionDenied, dto.PermissionPrompt}, state) {
+		return errors.Errorf("unsupported permission state %q", state)
+	}
+	_, err := p.runProgram(fmt.Sprintf("setPermission('%s', '%s', '%s'%s)", escapeJSString(origin), escapeJSString(name), escapeJSString(state), p.addArgs(opts)))
+	return err
+}
+
+// ClearPermissions resets all permissions set within the session (including BrowserOpts.GrantPermissions)
+func (p *program) ClearPermissions(opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall0("clearPermissions", opts...))
+	return err
+}
+
+// SetColorScheme emulates prefers-color-scheme media feature (dto.ColorSchemeDark, dto.ColorSchemeLight or dto.ColorSchemeNoPreference)
+func (p *program) SetColorScheme(scheme string, opts ...ActionOption) error {
+	if !lo.Contains([]string{dto.ColorSchemeDark, dto.ColorSchemeLight, dto.ColorSchemeNoPreference}, scheme) {
+		return errors.Errorf("unsupported color scheme %q", scheme)
+	}
+	_, err := p.runProgram(p.functionCall1("setColorScheme", scheme, opts...))
+	return err
+}
+
+// SetReducedMotion emulates prefers-reduced-motion media feature
+func (p *program) SetReducedMotion(reduce bool, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("setReducedMotion(%t%s)", reduce, p.addArgs(opts)))
+	return err
+}
+
+// EmulatePrintMedia makes the page render with @media print styles (until disabled),
+// so that following screenshots capture the print-friendly view
+func (p *program) EmulatePrintMedia(enable bool, opts ...ActionOption) error {
+	_, err := p.runProgram(fmt.Sprintf("emulatePrintMedia(%t%s)", enable, p.addArgs(opts)))
+	return err
+}
+
+// AddInitScript registers script to run before page scripts on each navigation for the rest of the session's lifetime
+func (p *program) AddInitScript(script string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall1("addInitScript", script, opts...))
+	return err
+}
+
+// GetAccessibilityTree returns accessibility tree of the page, it is a compact semantic model of the page
+// (better suited for LLM grounding than raw HTML)
+func (p *program) GetAccessibilityTree(opts ...ActionOption) (dto.AXNode, error) {
+	var root dto.AXNode
+	res, err := p.runProgram(p.functionCall0("getAccessibilityTree", opts...))
+	if err != nil {
+		return root, err
+	}
+	if err := decodeValue(res.Value, &root); err != nil {
+		return root, err
+	}
+	return root, nil
+}
+
+func (p *program) GetFormFields(formSelector string, opts ...ActionOption) ([]dto.FormField, error) {
+	res, err := p.runProgram(p.functionCall1("getFormFields", formSelector, opts...))
+	if err != nil {
+		return nil, err
+	}
+	var fields []dto.FormField
+	if err := decodeValue(res.Value, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// CompleteForm fills form fields matched by their names with the provided values
+// and optionally submits the form waiting for the navigation
+func (p *program) CompleteForm(formSelector string, values map[string]string, submit bool, opts ...ActionOption) error {
+	fields, err := p.GetFormFields(formSelector, opts...)
+	if err != nil {
+		return err
+	}
+	fieldNames := lo.SliceToMap(fields, func(field dto.FormField) (string, bool) {
+		return field.Name, true
+	})
+	names := lo.Keys(values)
+	sort.Strings(names)
+	if unmatched := lo.Filter(names, func(name string, _ int) bool { return !fieldNames[name] }); len(unmatched) > 0 {
+		return errors.Errorf("form %q has no fields matching values: %s", formSelector, strings.Join(unmatched, ", "))
+	}
+	for _, name := range names {
+		if err := p.SetValueN(formFieldSelector(formSelector, name), 0, values[name], opts...); err != nil {
+			return errors.Wrapf(err, "failed to set value of field %q", name)
+		}
+	}
+	if !submit {
+		return nil
+	}
+	// submit and wait in a single program, otherwise a fast navigation may finish before the wait starts
+	_, err = p.runProgram(fmt.Sprintf(`
+			%s
+			%s`,
+		p.functionCall1("submit", formSelector, opts...),
+		p.functionCall0("waitForNavigation", opts...)))
+	return err
+}
+
+// cssStringEscaper escapes value of a double-quoted CSS string (line breaks are only allowed as hex escapes)
+var cssStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\a `, "\r", `\d `)
+
+// formFieldSelector returns selector of the field named name in the form matched by formSelector,
+// scoping every alternative of a selector list (e.g. "#login, #signup") separately
+func formFieldSelector(formSelector, name string) string {
+	field := `[name="` + cssStringEscaper.Replace(name) + `"]`
+	return strings.Join(lo.Map(splitSelectorList(formSelector), func(form string, _ int) string {
+		return form + " " + field
+	}), ", ")
+}
+
+// splitSelectorList splits selector list into its selectors ignoring commas inside quotes, brackets and parentheses
+// (e.g. [data-x="a,b"] or :is(h1, h2))
+func splitSelectorList(selector string) []string {
+	var res []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(selector); i++ {
+		switch c := selector[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			res = append(res, strings.TrimSpace(selector[start:i]))
+			start = i + 1
+		}
+	}
+	return append(res, strings.TrimSpace(selector[start:]))
+}
+
 func (p *program) GetURL(opts ...ActionOption) (string, error) {
 	res, err := p.runProgram(p.functionCall0("getURL", opts...))
 	if err != nil {
@@ -567,26 +1881,262 @@ func (p *program) GetURL(opts ...ActionOption) (string, error) {
 	return res.Value.(string), nil
 }
 
+func (p *program) GetPageTitle(opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall0("getPageTitle", opts...))
+	if err != nil {
+		return "", err
+	}
+	return valueToString(res.Value)
+}
+
+// GetTitle returns title of the document (same as GetPageTitle)
+func (p *program) GetTitle(opts ...ActionOption) (string, error) {
+	return p.GetPageTitle(opts...)
+}
+
+// SelectText selects the whole text contents of the element (e.g. before copying or reading it with GetSelectedText)
+func (p *program) SelectText(selector string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall1("selectText", selector, opts...))
+	return err
+}
+
+// GetSelectedText returns text of the current selection on the page (empty if nothing is selected)
+func (p *program) GetSelectedText(opts ...ActionOption) (string, error) {
+	res, err := p.runProgram(p.functionCall0("getSelectedText", opts...))
+	if err != nil {
+		return "", err
+	}
+	return valueToString(res.Value)
+}
+
+// SetTitle changes title of the document (e.g. to tell tabs apart in multi-tab automations)
+func (p *program) SetTitle(title string, opts ...ActionOption) error {
+	_, err := p.runProgram(p.functionCall1("setTitle", title, opts...))
+	return err
+}
+
+// GetPageMeta returns charset, language, title and canonical URL of the page in one call
+func (p *program) GetPageMeta(opts ...ActionOption) (dto.PageMeta, error) {
+	var meta dto.PageMeta
+	res, err := p.runProgram(p.functionCall0("getPageMeta", opts...))
+	if err != nil {
+		return meta, err
+	}
+	if err := decodeValue(res.Value, &meta); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+// GetImages returns source, alt text, natural dimensions and load state of all images on the page
+// (use WithSelector to limit to images inside the matching element)
+func (p *program) GetImages(opts ...ActionOption) ([]dto.ImageInfo, error) {
+	res, err := p.runProgram(p.functionCall0("getImages", opts...))
+	if err != nil {
+		return nil, err
+	}
+	var images []dto.ImageInfo
+	if err := decodeValue(res.Value, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// GetElementMetrics returns length of the element's visible text and which part of it is within the viewport
+// (e.g. for content quality checks)
+func (p *program) GetElementMetrics(selector string, opts ...ActionOption) (dto.ElementMetrics, error) {
+	var metrics dto.ElementMetrics
+	res, err := p.runProgram(p.functionCall1("getElementMetrics", selector, opts...))
+	if err != nil {
+		return metrics, err
+	}
+	if err := decodeValue(res.Value, &metrics); err != nil {
+		return metrics, err
+	}
+	return metrics, nil
+}
+
+// GetWebSocketMessages returns frames sent and received over WebSocket connections with URL matching the regular expression,
+// the session must be started with BrowserOpts.RecordWebSockets (see dto.WSMessage for payload limits);
+// pattern must be compatible with both Go and JavaScript regular expressions
+func (p *program) GetWebSocketMessages(urlPattern string, opts ...ActionOption) ([]dto.WSMessage, error) {
+	if _, err := regexp.Compile(urlPattern); err != nil {
+		return nil, errors.Wrapf(err, "invalid URL pattern %q", urlPattern)
+	}
+	res, err := p.runProgram(p.functionCall1("getWebSocketMessages", urlPattern, opts...))
+	if err != nil {
+		return nil, err
+	}
+	var messages []dto.WSMessage
+	if err := decodeValue(res.Value, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetNetworkStats returns number of requests and bytes transferred since the last navigation broken down by resource type
+// (e.g. to correlate proxy costs with pages)
+func (p *program) GetNetworkStats(opts ...ActionOption) (dto.NetworkStats, error) {
+	var stats dto.NetworkStats
+	res, err := p.runProgram(p.functionCall0("getNetworkStats", opts...))
+	if err != nil {
+		return stats, err
+	}
+	if err := decodeValue(res.Value, &stats); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// Info returns snapshot of the current session state (combining current page URL and title with locally accumulated stats)
+func (p *program) Info(opts ...ActionOption) (dto.SessionInfo, error) {
+	p.statsMu.Lock()
+	info := dto.SessionInfo{
+		SessionID:    p.sessionID,
+		Cost:         p.cost,
+		CommandCount: p.commandCount,
+		UsedProxy:    p.usedProxy,
+	}
+	p.statsMu.Unlock()
+	url, err := p.GetURL(opts...)
+	if err != nil {
+		return info, err
+	}
+	info.URL = url
+	title, err := p.GetPageTitle(opts...)
+	if err != nil {
+		return info, err
+	}
+	info.Title = title
+	return info, nil
+}
+
+// GetCapabilities returns browser version and features of the session (useful to log environment with bug reports)
+func (p *program) GetCapabilities(opts ...ActionOption) (dto.SessionCapabilities, error) {
+	var capabilities dto.SessionCapabilities
+	res, err := p.runProgram(p.functionCall0("getCapabilities", opts...))
+	if err != nil {
+		return capabilities, err
+	}
+	if err := decodeValue(res.Value, &capabilities); err != nil {
+		return capabilities, err
+	}
+	return capabilities, nil
+}
+
+func (p *program) GetCookies(opts ...ActionOption) ([]dto.BrowserCookie, error) {
+	res, err := p.runProgram(p.functionCall0("getCookies", opts...))
+	if err != nil {
+		return nil, err
+	}
+	var cookies []dto.BrowserCookie
+	if err := decodeValue(res.Value, &cookies); err != nil {
+		return nil, err
+	}
+	p.lastCookies = cookies
+	return cookies, nil
+}
+
+// SetCookies sets all cookies with a single command (e.g. to restore state saved with GetCookies)
+func (p *program) SetCookies(cookies []dto.BrowserCookie, opts ...ActionOption) error {
+	cookiesJSON, err := json.Marshal(lo.Ternary(cookies == nil, []dto.BrowserCookie{}, cookies))
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal cookies")
+	}
+	_, err = p.runProgram(fmt.Sprintf("setCookies(%s%s)", cookiesJSON, p.addArgs(opts)))
+	return err
+}
+
+// CookieChanges returns cookie changes detected after each command (requires WithCookieTracking option)
+func (p *program) CookieChanges() []dto.CookieDelta {
+	return p.cookieChanges
+}
+
 func (p *program) functionCall0(name string, opts ...ActionOption) string {
-	return fmt.Sprintf("%s(%s)", name, p.addArgs(opts))
+	return fmt.Sprintf("%s(%s)", name, strings.TrimPrefix(p.addArgs(opts), ", "))
 }
 
 func (p *program) functionCall1(name, arg1 string, opts ...ActionOption) string {
-	return fmt.Sprintf("%s('%s'%s)", name, arg1, p.addArgs(opts))
+	return fmt.Sprintf("%s('%s'%s)", name, escapeJSString(arg1), p.addArgs(opts))
 }
 
 func (p *program) functionCall2(name, arg1, arg2 string, opts ...ActionOption) string {
-	return fmt.Sprintf("%s('%s', '%s'%s)", name, arg1, arg2, p.addArgs(opts))
+	return fmt.Sprintf("%s('%s', '%s'%s)", name, escapeJSString(arg1), escapeJSString(arg2), p.addArgs(opts))
+}
+
+// appendArgs appends options to the arguments of the function call program ends with
+func (p *program) appendArgs(program string, opts []ActionOption) (string, error) {
+	args := p.addArgs(opts)
+	if args == "" {
+		return program, nil
+	}
+	call, found := strings.CutSuffix(strings.TrimSpace(program), ")")
+	if !found {
+		return "", errors.Errorf("failed to apply options to program not ending with a function call: %s", program)
+	}
+	if strings.HasSuffix(strings.TrimSpace(call), "(") {
+		args = strings.TrimPrefix(args, ", ")
+	}
+	return call + args + ")", nil
 }
 
 func (p *program) addArgs(opts []ActionOption) string {
 	var addArgs []string
-	for _, opt := range opts {
+	for _, opt := range slices.Concat(p.defaultActionOptions, opts) {
 		addArgs = opt(addArgs)
 	}
+	addArgs = lo.Map(mergeArgs(addArgs), func(arg string, _ int) string {
+		return escapeJSString(arg)
+	})
 	addArgsString := ""
 	if len(addArgs) > 0 {
 		addArgsString = ", " + fmt.Sprintf("'%s'", strings.Join(addArgs, "','"))
 	}
 	return addArgsString
 }
+
+// mergeArgs deduplicates action arguments: for keyed arguments (e.g. timeout:10s) the last one wins
+// keeping position of the first one, WithoutTimeout and WithTimeout override each other the same way
+func mergeArgs(args []string) []string {
+	var res []string
+	positions := map[string]int{}
+	for _, arg := range args {
+		key := argKey(arg)
+		if pos, found := positions[key]; found {
+			res[pos] = arg
+			continue
+		}
+		positions[key] = len(res)
+		res = append(res, arg)
+	}
+	return res
+}
+
+// takeClientArg checks whether client-side argument is set (returning its value for keyed arguments, e.g. key:value)
+// and strips it from the options sent to the backend
+func (p *program) takeClientArg(key string, opts []ActionOption) (string, bool, []ActionOption) {
+	var args []string
+	for _, opt := range slices.Concat(p.defaultActionOptions, opts) {
+		args = opt(args)
+	}
+	arg, found := lo.Find(mergeArgs(args), func(arg string) bool {
+		return argKey(arg) == key
+	})
+	if !found {
+		return "", false, opts
+	}
+	_, value, _ := strings.Cut(arg, ":")
+	return value, true, append(slices.Clone(opts), func(args []string) []string {
+		return lo.Reject(args, func(arg string, _ int) bool {
+			return argKey(arg) == key
+		})
+	})
+}
+
+func argKey(arg string) string {
+	if arg == "withoutTimeout" {
+		return "timeout"
+	}
+	key, _, _ := strings.Cut(arg, ":")
+	return key
+}
@@ -0,0 +1,1249 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func TestWaitForStable(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	err := p.WaitForStable("#menu", 300)
+	Expect(err).To(BeNil())
+
+	err = p.WaitForStable("#menu", 500, WithTimeout("5s"))
+	Expect(err).To(BeNil())
+
+	Expect(c.programs).To(Equal([]string{
+		"waitForStable('#menu', 300)",
+		"waitForStable('#menu', 500, 'timeout:5s')",
+	}))
+}
+
+func TestWaitForFonts(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.WaitForFonts("5s")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"waitForFonts('5s')"}))
+}
+
+func TestWaitForTextStable(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("1,204 visitors"))
+
+	text, err := p.WaitForTextStable("#counter", 500)
+	Expect(err).To(BeNil())
+	Expect(text).To(Equal("1,204 visitors"))
+
+	_, err = p.WaitForTextStable("#counter", 1000, WithTimeout("5s"))
+	Expect(err).To(BeNil())
+
+	Expect(c.programs).To(Equal([]string{
+		"waitForTextStable('#counter', 500)",
+		"waitForTextStable('#counter', 1000, 'timeout:5s')",
+	}))
+}
+
+func TestWithNoProxy(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.Navigate("http://localhost:8080/health", WithNoProxy())).To(Succeed())
+	Expect(p.Navigate("https://example.com", WithTimeout("10s"), WithNoProxy())).To(Succeed())
+	Expect(c.programs).To(Equal([]string{
+		"navigate('http://localhost:8080/health', 'noProxy')",
+		"navigate('https://example.com', 'timeout:10s','noProxy')",
+	}))
+}
+
+func TestWithPierceShadow(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("Buy"))
+
+	Expect(p.Click("#buy", WithPierceShadow())).To(Succeed())
+	_, err := p.GetInnerText("#buy", WithPierceShadow())
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{
+		"click('#buy', 'pierceShadow')",
+		"getInnerText('#buy', 'pierceShadow')",
+	}))
+}
+
+func TestScreenshotOnError(t *testing.T) {
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		if strings.HasPrefix(msg.Program, "takeScreenshot") {
+			return &dto.BrowserMessageOut{Screenshots: map[string][]byte{"failure": []byte("png")}}, nil
+		}
+		return &dto.BrowserMessageOut{Error: "element not found"}, nil
+	}, WithScreenshotOnError("failure"))
+
+	err := p.Click("#missing")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal("element not found"))
+
+	var baasErr *BaasError
+	Expect(errors.As(err, &baasErr)).To(BeTrue())
+	Expect(baasErr.Screenshot).To(Equal([]byte("png")))
+	Expect(c.programs).To(Equal([]string{"click('#missing')", "takeScreenshot('failure')"}))
+}
+
+func TestAssert(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(true))
+
+	err := p.Assert("document.title.includes('Dashboard')")
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{`evaluateJS('document.title.includes(\'Dashboard\')')`}))
+
+	p, _ = newMockProgram(t, valueResponse(false))
+	err = p.Assert("document.title.includes('Dashboard')")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal("assertion failed: document.title.includes('Dashboard')"))
+}
+
+func TestRetryStale(t *testing.T) {
+	attempts := 0
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		attempts++
+		if attempts == 1 {
+			return &dto.BrowserMessageOut{Error: "stale element reference: element is not attached to the page document"}, nil
+		}
+		return &dto.BrowserMessageOut{}, nil
+	}, WithRetryStale())
+
+	err := p.Click("#submit")
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{"click('#submit')", "click('#submit')"}))
+
+	p, c = newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Error: "element not found"}, nil
+	}, WithRetryStale())
+	err = p.Click("#submit")
+	Expect(err).NotTo(BeNil())
+	Expect(c.programs).To(HaveLen(1))
+}
+
+func TestInfo(t *testing.T) {
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		res := &dto.BrowserMessageOut{UsedProxy: "proxy:8080"}
+		res.Meta.Cost = 0.25
+		switch msg.Program {
+		case "getURL()":
+			res.Value = "https://example.com/dashboard"
+		case "getPageTitle()":
+			res.Value = "Dashboard"
+		}
+		return res, nil
+	})
+
+	Expect(p.Click("#login")).To(BeNil())
+	Expect(p.Click("#dashboard")).To(BeNil())
+
+	info, err := p.Info()
+	Expect(err).To(BeNil())
+	Expect(info).To(Equal(dto.SessionInfo{
+		SessionID:    "test-session",
+		URL:          "https://example.com/dashboard",
+		Title:        "Dashboard",
+		Cost:         0.5,
+		CommandCount: 2,
+		UsedProxy:    "proxy:8080",
+	}))
+	Expect(c.programs[2:]).To(Equal([]string{"getURL()", "getPageTitle()"}))
+}
+
+func TestSetNetworkConditions(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	err := p.SetNetworkConditions(dto.Slow3G.DownloadKbps, dto.Slow3G.UploadKbps, dto.Slow3G.LatencyMs)
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{"setNetworkConditions(400, 400, 2000)"}))
+}
+
+func TestNetworkThrottleAtStart(t *testing.T) {
+	started := startedConfig(t, Config{NetworkThrottle: lo.ToPtr(dto.Slow3G)})
+	Expect(started.Browser.NetworkThrottle).To(Equal(lo.ToPtr(dto.Slow3G)))
+}
+
+func TestSetOffline(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.SetOffline(true)).To(Succeed())
+	Expect(p.SetOffline(false)).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"setOffline(true)", "setOffline(false)"}))
+}
+
+func TestSetWindowBounds(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.SetWindowBounds(0, 0, 1280, 800)).To(Succeed())
+	Expect(p.SetWindowBounds(100, 50, 0, 800)).NotTo(Succeed())
+	Expect(p.SetWindowBounds(100, 50, 1280, -1)).NotTo(Succeed())
+	Expect(c.programs).To(Equal([]string{"setWindowBounds(0, 0, 1280, 800)"}))
+}
+
+func TestGetFormFields(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse([]any{
+		map[string]any{"name": "email", "type": "email", "value": "", "required": true},
+		map[string]any{"name": "remember", "type": "checkbox", "value": "on", "required": false},
+	}))
+
+	fields, err := p.GetFormFields("#login")
+	Expect(err).To(BeNil())
+	Expect(fields).To(Equal([]dto.FormField{
+		{Name: "email", Type: "email", Value: "", Required: true},
+		{Name: "remember", Type: "checkbox", Value: "on", Required: false},
+	}))
+	Expect(c.programs).To(Equal([]string{"getFormFields('#login')"}))
+}
+
+func TestCompleteForm(t *testing.T) {
+	fields := []any{
+		map[string]any{"name": "email", "type": "email"},
+		map[string]any{"name": "password", "type": "password"},
+	}
+	p, c := newMockProgram(t, valueResponse(fields))
+
+	err := p.CompleteForm("#login", map[string]string{"email": "user@example.com", "username": "user", "name": "User"}, true)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal(`form "#login" has no fields matching values: name, username`))
+	Expect(c.programs).To(Equal([]string{"getFormFields('#login')"}))
+
+	p, c = newMockProgram(t, valueResponse(fields))
+	err = p.CompleteForm("#login", map[string]string{"password": "it's secret", "email": "user@example.com"}, true)
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(HaveLen(4))
+	Expect(c.programs[:3]).To(Equal([]string{
+		"getFormFields('#login')",
+		`setValueN('#login [name="email"]', 0, 'user@example.com')`,
+		`setValueN('#login [name="password"]', 0, 'it\'s secret')`,
+	}))
+	Expect(strings.Fields(c.programs[3])).To(Equal([]string{"submit('#login')", "waitForNavigation()"}))
+
+	p, c = newMockProgram(t, valueResponse([]any{map[string]any{"name": `user"name`}}))
+	err = p.CompleteForm(`#login, form[data-flow="sign,up"]`, map[string]string{`user"name`: "bob"}, false)
+	Expect(err).To(BeNil())
+	Expect(c.programs[1:]).To(Equal([]string{
+		`setValueN('#login [name="user\\"name"], form[data-flow="sign,up"] [name="user\\"name"]', 0, 'bob')`,
+	}))
+}
+
+func TestAddInitScript(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.AddInitScript("window.seed = 'value'")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{`addInitScript('window.seed = \'value\'')`}))
+}
+
+func TestInitScriptsAtStart(t *testing.T) {
+	script := "window.__testMode = true"
+	started := startedConfig(t, Config{InitScripts: []string{script}})
+	Expect(started.Browser.InitScripts).To(Equal([]string{script}))
+}
+
+func TestStealthAtStart(t *testing.T) {
+	started := startedConfig(t, Config{Stealth: true, StealthOptions: &dto.StealthOptions{WebGLVendor: lo.ToPtr(false)}})
+	Expect(started.Browser.Stealth).To(Equal(lo.ToPtr(true)))
+	Expect(started.Browser.StealthOptions).To(Equal(&dto.StealthOptions{WebGLVendor: lo.ToPtr(false)}))
+}
+
+func TestBrowserExtraAtStart(t *testing.T) {
+	started := startedConfig(t, Config{BrowserExtra: map[string]any{"newFeature": true}})
+	Expect(started.Browser.Extra).To(Equal(map[string]any{"newFeature": true}))
+}
+
+func TestExecuteTyped(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("text"))
+	s, err := p.ExecuteString("getInnerText('h1')")
+	Expect(err).To(BeNil())
+	Expect(s).To(Equal("text"))
+	_, err = p.ExecuteInt("getInnerText('h1')")
+	Expect(err).NotTo(BeNil())
+	s, err = p.ExecuteString("getInnerText('h1')", WithTimeout("5s"))
+	Expect(err).To(BeNil())
+	Expect(s).To(Equal("text"))
+	Expect(c.programs).To(Equal([]string{
+		"getInnerText('h1')",
+		"getInnerText('h1')",
+		"getInnerText('h1', 'timeout:5s')",
+	}))
+
+	c.programs = nil
+	_, err = p.Execute("getURL()", WithTimeout("5s"))
+	Expect(err).To(BeNil())
+	_, err = p.Execute("const title = getPageTitle(); title", WithTimeout("5s"))
+	Expect(err).NotTo(BeNil())
+	Expect(c.programs).To(Equal([]string{"getURL('timeout:5s')"}))
+
+	p, _ = newMockProgram(t, valueResponse(float64(42)))
+	i, err := p.ExecuteInt("countElements('li')")
+	Expect(err).To(BeNil())
+	Expect(i).To(Equal(42))
+	_, err = p.ExecuteBool("countElements('li')")
+	Expect(err).NotTo(BeNil())
+
+	p, _ = newMockProgram(t, valueResponse(1.5))
+	f, err := p.ExecuteFloat("evaluateJS('1.5')")
+	Expect(err).To(BeNil())
+	Expect(f).To(Equal(1.5))
+	_, err = p.ExecuteInt("evaluateJS('1.5')")
+	Expect(err).NotTo(BeNil())
+	_, err = p.ExecuteString("evaluateJS('1.5')")
+	Expect(err).NotTo(BeNil())
+
+	p, _ = newMockProgram(t, valueResponse(true))
+	b, err := p.ExecuteBool("isElementPresent('h1')")
+	Expect(err).To(BeNil())
+	Expect(b).To(BeTrue())
+	_, err = p.ExecuteFloat("isElementPresent('h1')")
+	Expect(err).NotTo(BeNil())
+
+	p, _ = newMockProgram(t, valueResponse(map[string]any{"name": "item", "tags": []any{"a", "b"}}))
+	var obj struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	Expect(p.ExecuteJSON("evaluateJS('item')", &obj)).To(Succeed())
+	Expect(obj.Name).To(Equal("item"))
+	Expect(obj.Tags).To(Equal([]string{"a", "b"}))
+	var list []string
+	Expect(p.ExecuteJSON("evaluateJS('item')", &list)).NotTo(Succeed())
+}
+
+func TestWaitForManualIntervention(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(true))
+
+	err := p.WaitForManualIntervention("#captcha", "5m")
+	Expect(errors.Is(err, ErrManualInterventionRequired)).To(BeTrue())
+	Expect(c.programs).To(Equal([]string{"isElementPresent('#captcha')"}))
+
+	p, c = newMockProgram(t, valueResponse(true))
+	p.cfg.LocalDebug = true
+	Expect(p.WaitForManualIntervention("#captcha", "5m")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"isElementPresent('#captcha')", "waitForManualIntervention('#captcha', '5m')"}))
+
+	p, c = newMockProgram(t, valueResponse(false))
+	Expect(p.WaitForManualIntervention("#captcha", "5m")).To(Succeed())
+	Expect(c.programs).To(HaveLen(1))
+}
+
+type screenshotTestReporter struct {
+	testReporter
+	screenshots map[string][]byte
+}
+
+func (r *screenshotTestReporter) Screenshot(name string, data []byte) {
+	r.screenshots[name] = data
+}
+
+func TestReportScreenshots(t *testing.T) {
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Screenshots: map[string][]byte{"after-click": []byte("png")}}, nil
+	}, WithReportScreenshots())
+	reporter := &screenshotTestReporter{screenshots: map[string][]byte{}}
+	p.reporter = reporter
+
+	Expect(p.Click("#submit")).To(Succeed())
+	Expect(reporter.screenshots).To(Equal(map[string][]byte{"after-click": []byte("png")}))
+}
+
+func TestGetPendingDownloadName(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("report.csv"))
+	name, err := p.GetPendingDownloadName()
+	Expect(err).To(BeNil())
+	Expect(name).To(Equal("report.csv"))
+	Expect(c.programs).To(Equal([]string{"getPendingDownloadName()"}))
+
+	p, _ = newMockProgram(t, valueResponse(nil))
+	name, err = p.GetPendingDownloadName()
+	Expect(err).To(BeNil())
+	Expect(name).To(BeEmpty())
+}
+
+func TestResetBrowserState(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.ResetBrowserState()).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"resetBrowserState()"}))
+}
+
+func TestLastNavigationStatus(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(float64(302)))
+	status, err := p.LastNavigationStatus()
+	Expect(err).To(BeNil())
+	Expect(status).To(Equal(302))
+	Expect(c.programs).To(Equal([]string{"lastNavigationStatus()"}))
+
+	p, _ = newMockProgram(t, valueResponse("302"))
+	_, err = p.LastNavigationStatus()
+	Expect(err).NotTo(BeNil())
+}
+
+func TestWaitForAny(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(".error-banner"))
+
+	matched, err := p.WaitForAny([]string{"#success", ".error-banner", "[data-state='failed']"}, WithTimeout("10s"))
+	Expect(err).To(BeNil())
+	Expect(matched).To(Equal(".error-banner"))
+	Expect(c.programs).To(Equal([]string{`waitForAny(['#success','.error-banner','[data-state=\'failed\']'], 'timeout:10s')`}))
+}
+
+func TestStepScreenshots(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "steps")
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Screenshots: map[string][]byte{
+			"step-001": []byte("first"),
+			"step-002": []byte("second"),
+		}}, nil
+	}, WithStepScreenshots(dir))
+
+	Expect(p.Navigate("https://example.com")).To(Succeed())
+	Expect(p.Click("#next")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{
+		"navigate('https://example.com')",
+		"takeScreenshot('step-001')",
+		"click('#next')",
+		"takeScreenshot('step-002')",
+	}))
+
+	data, err := os.ReadFile(filepath.Join(dir, "step-001.png"))
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(Equal("first"))
+	data, err = os.ReadFile(filepath.Join(dir, "step-002.png"))
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(Equal("second"))
+
+	// failure to save is reported once and disables step screenshots
+	reporter := &recordingReporter{}
+	p, c = newMockProgram(t, nil, func(p *program) { p.reporter = reporter }, WithStepScreenshots(filepath.Join(dir, "step-001.png")))
+	Expect(p.Click("#next")).To(Succeed())
+	Expect(p.Click("#next")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"click('#next')", "click('#next')"}))
+	Expect(lo.Filter(reporter.messages, func(msg string, _ int) bool {
+		return strings.Contains(msg, "step screenshots are disabled")
+	})).To(HaveLen(1))
+}
+
+func TestActionOptionsMerge(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.Click("#a", WithTimeout("2s"), WithIncludeInvisible(), WithTimeout("5s"), WithIncludeInvisible())).To(Succeed())
+	Expect(p.Click("#b", WithoutTimeout(), WithTimeout("5s"))).To(Succeed())
+	Expect(p.Click("#c", WithTimeout("5s"), WithoutTimeout())).To(Succeed())
+	Expect(c.programs).To(Equal([]string{
+		"click('#a', 'timeout:5s','includeInvisible')",
+		"click('#b', 'timeout:5s')",
+		"click('#c', 'withoutTimeout')",
+	}))
+}
+
+func TestScrollElement(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.ScrollElement("#chat-log", 0, -250)).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"scrollElement('#chat-log', 0, -250)"}))
+}
+
+func TestGetPageSource(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(`<html><body><div id="app"></div></body></html>`))
+
+	source, err := p.GetPageSource()
+	Expect(err).To(BeNil())
+	Expect(source).To(Equal(`<html><body><div id="app"></div></body></html>`))
+	Expect(c.programs).To(Equal([]string{"getPageSource()"}))
+}
+
+func TestMediaEmulation(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.SetColorScheme(dto.ColorSchemeDark)).To(Succeed())
+	Expect(p.SetReducedMotion(true)).To(Succeed())
+	Expect(p.SetColorScheme("sepia")).NotTo(Succeed())
+	Expect(c.programs).To(Equal([]string{"setColorScheme('dark')", "setReducedMotion(true)"}))
+}
+
+func TestPermissions(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.SetPermission("https://maps.example.com", "geolocation", dto.PermissionGranted)).To(Succeed())
+	Expect(p.SetPermission("https://example.com", "notifications", "allow")).NotTo(Succeed())
+	Expect(p.ClearPermissions()).To(Succeed())
+	Expect(c.programs).To(Equal([]string{
+		"setPermission('https://maps.example.com', 'geolocation', 'granted')",
+		"clearPermissions()",
+	}))
+}
+
+func TestGrantPermissionsAtStart(t *testing.T) {
+	started := startedConfig(t, Config{GrantPermissions: []string{"geolocation", "notifications"}})
+	Expect(started.Browser.GrantPermissions).To(Equal([]string{"geolocation", "notifications"}))
+}
+
+func TestEmulatePrintMedia(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.EmulatePrintMedia(true)).To(Succeed())
+	Expect(p.EmulatePrintMedia(false)).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"emulatePrintMedia(true)", "emulatePrintMedia(false)"}))
+}
+
+func TestGetConsoleErrors(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse([]any{
+		"Uncaught TypeError: Cannot read properties of undefined (reading 'id')",
+		"Failed to load resource: the server responded with a status of 404 ()",
+	}))
+
+	consoleErrors, err := p.GetConsoleErrors()
+	Expect(err).To(BeNil())
+	Expect(consoleErrors).To(Equal([]string{
+		"Uncaught TypeError: Cannot read properties of undefined (reading 'id')",
+		"Failed to load resource: the server responded with a status of 404 ()",
+	}))
+	Expect(c.programs).To(Equal([]string{"getConsoleErrors()"}))
+
+	p, _ = newMockProgram(t, valueResponse(nil))
+	consoleErrors, err = p.GetConsoleErrors()
+	Expect(err).To(BeNil())
+	Expect(consoleErrors).To(BeEmpty())
+}
+
+func TestNavigateResult(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{
+		"url":    "https://example.com/home",
+		"status": json.Number("200"),
+		"redirectChain": []any{
+			map[string]any{"url": "http://example.com", "status": json.Number("301")},
+			map[string]any{"url": "https://example.com", "status": json.Number("302")},
+		},
+	}))
+
+	result, err := p.NavigateResult("http://example.com")
+	Expect(err).To(BeNil())
+	Expect(result).To(Equal(dto.NavigationResult{
+		URL:    "https://example.com/home",
+		Status: 200,
+		RedirectChain: []dto.RedirectHop{
+			{URL: "http://example.com", Status: 301},
+			{URL: "https://example.com", Status: 302},
+		},
+	}))
+	Expect(c.programs).To(Equal([]string{"navigateResult('http://example.com')"}))
+}
+
+func TestForEachURL(t *testing.T) {
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		switch msg.Program {
+		case "navigate('https://example.com/broken')":
+			return &dto.BrowserMessageOut{Error: "net::ERR_NAME_NOT_RESOLVED"}, nil
+		case "getInnerText('h1')":
+			return &dto.BrowserMessageOut{Value: "Title"}, nil
+		}
+		return &dto.BrowserMessageOut{}, nil
+	})
+
+	results, err := p.ForEachURL([]string{"https://example.com/a", "https://example.com/broken", "https://example.com/b"}, "getInnerText('h1')", nil)
+	Expect(err).To(BeNil())
+	Expect(results).To(Equal([]dto.URLResult{
+		{URL: "https://example.com/a", Value: "Title"},
+		{URL: "https://example.com/broken", Error: "net::ERR_NAME_NOT_RESOLVED"},
+		{URL: "https://example.com/b", Value: "Title"},
+	}))
+	Expect(c.programs).To(Equal([]string{
+		"navigate('https://example.com/a')",
+		"getInnerText('h1')",
+		"navigate('https://example.com/broken')",
+		"navigate('https://example.com/b')",
+		"getInnerText('h1')",
+	}))
+
+	c.programs = nil
+	_, err = p.ForEachURL([]string{"https://example.com/a b"}, "getInnerText('h1')", []ActionOption{WithEncodeURL()}, WithTimeout("5s"))
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{
+		"navigate('https://example.com/a%20b')",
+		"getInnerText('h1', 'timeout:5s')",
+	}))
+}
+
+func TestGetNavigationBody(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(`{"items":[1,2]}`))
+
+	body, err := p.GetNavigationBody()
+	Expect(err).To(BeNil())
+	Expect(body).To(Equal(`{"items":[1,2]}`))
+	Expect(c.programs).To(Equal([]string{"getNavigationBody()"}))
+}
+
+func TestAssertGoneFor(t *testing.T) {
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		switch {
+		case strings.Contains(msg.Program, ".toast"):
+			return &dto.BrowserMessageOut{Error: "element .toast appeared after 1.2s"}, nil
+		case strings.Contains(msg.Program, ".modal"):
+			return &dto.BrowserMessageOut{Error: "session not found: test-session"}, nil
+		case strings.Contains(msg.Program, ".banner"):
+			return nil, errors.New("failed to fetch the page: connection refused")
+		}
+		return &dto.BrowserMessageOut{}, nil
+	})
+
+	Expect(p.AssertGoneFor(".error-banner", "3s")).To(Succeed())
+
+	err := p.AssertGoneFor(".toast", "5s")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring(`".toast" did not stay absent for 5s`))
+
+	// session and transport failures are not assertion failures
+	err = p.AssertGoneFor(".modal", "5s")
+	Expect(errors.Is(err, ErrSessionExpired)).To(BeTrue())
+	Expect(err.Error()).To(Equal("session not found: test-session"))
+	err = p.AssertGoneFor(".banner", "5s")
+	Expect(err.Error()).To(Equal("failed to fetch the page: connection refused"))
+
+	Expect(c.programs).To(Equal([]string{
+		"assertGoneFor('.error-banner', '3s')",
+		"assertGoneFor('.toast', '5s')",
+		"assertGoneFor('.modal', '5s')",
+		"assertGoneFor('.banner', '5s')",
+	}))
+}
+
+func TestAssertAttribute(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("true"))
+
+	err := p.AssertAttribute("#agree", "aria-checked", "true")
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{"getAttribute('#agree', 'aria-checked')"}))
+
+	err = p.AssertAttribute("#agree", "aria-checked", "false")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal(`attribute "aria-checked" of "#agree" is "true", expected "false"`))
+
+	p, c = newMockProgram(t, valueResponse("btn btn-primary"))
+	err = p.AssertAttribute("#submit", "class", `\bbtn-primary\b`, WithRegexMatch(), WithTimeout("5s"))
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{"getAttribute('#submit', 'class', 'timeout:5s')"}))
+
+	err = p.AssertAttribute("#submit", "class", `^disabled`, WithRegexMatch())
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal(`attribute "class" of "#submit" is "btn btn-primary", expected to match "^disabled"`))
+}
+
+func TestEmptySessionID(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("https://example.com"))
+	p.sessionID = ""
+
+	_, err := p.GetURL()
+	Expect(errors.Is(err, ErrNoActiveSession)).To(BeTrue())
+	Expect(err.Error()).To(ContainSubstring("no active session"))
+	Expect(c.programs).To(BeEmpty())
+}
+
+func TestIsLoading(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(true))
+
+	loading, err := p.IsLoading()
+	Expect(err).To(BeNil())
+	Expect(loading).To(BeTrue())
+	Expect(c.programs).To(Equal([]string{"isLoading()"}))
+
+	p, _ = newMockProgram(t, valueResponse("not a bool"))
+	_, err = p.IsLoading()
+	Expect(err).NotTo(BeNil())
+}
+
+func TestGetAccessibilityTree(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{
+		"role": "RootWebArea",
+		"name": "Login",
+		"children": []any{
+			map[string]any{"role": "heading", "name": "Sign in"},
+			map[string]any{"role": "form", "name": "", "children": []any{
+				map[string]any{"role": "textbox", "name": "Email", "value": "user@example.com"},
+				map[string]any{"role": "button", "name": "Submit"},
+			}},
+		},
+	}))
+
+	tree, err := p.GetAccessibilityTree()
+	Expect(err).To(BeNil())
+	Expect(tree).To(Equal(dto.AXNode{
+		Role: "RootWebArea",
+		Name: "Login",
+		Children: []dto.AXNode{
+			{Role: "heading", Name: "Sign in"},
+			{Role: "form", Children: []dto.AXNode{
+				{Role: "textbox", Name: "Email", Value: "user@example.com"},
+				{Role: "button", Name: "Submit"},
+			}},
+		},
+	}))
+	Expect(c.programs).To(Equal([]string{"getAccessibilityTree()"}))
+}
+
+func TestMaxProgramBytes(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("ok"))
+	p.cfg.MaxProgramBytes = 32
+
+	_, err := p.Execute("log('short')")
+	Expect(err).To(BeNil())
+
+	_, err = p.Execute("log('" + strings.Repeat("x", 32) + "')")
+	Expect(errors.Is(err, ErrProgramTooLarge)).To(BeTrue())
+	Expect(err.Error()).To(Equal("program is 39 bytes, max allowed is 32: program too large"))
+	Expect(c.programs).To(Equal([]string{"log('short')"}))
+}
+
+func TestLlmReadImage(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("Revenue grew 12% in Q3"))
+
+	answer, err := p.LlmReadImage("canvas#chart", "What's the Q3 revenue growth?", WithTimeout("60s"))
+	Expect(err).To(BeNil())
+	Expect(answer).To(Equal("Revenue grew 12% in Q3"))
+	Expect(c.programs).To(HaveLen(1))
+	Expect(strings.Split(strings.TrimSpace(c.programs[0]), "\n\t\t\t")).To(Equal([]string{
+		"takeScreenshot('llm-read-image', 'timeout:60s','selector:canvas#chart')",
+		`llmReadImage('llm-read-image', 'What\'s the Q3 revenue growth?', 'timeout:60s')`,
+	}))
+
+	p, c = newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Error: "element not found"}, nil
+	})
+	_, err = p.LlmReadImage("canvas#chart", "What's the Q3 revenue growth?")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(HavePrefix(`failed to read image of "canvas#chart"`))
+	Expect(errors.Is(err, ErrElementNotFound)).To(BeTrue())
+	Expect(c.programs).To(HaveLen(1))
+}
+
+func TestKeepAlive(t *testing.T) {
+	var pings atomic.Int32
+	release := make(chan struct{})
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		if msg.Program == "getURL()" {
+			pings.Add(1)
+		} else {
+			<-release
+		}
+		return &dto.BrowserMessageOut{}, nil
+	}, WithKeepAlive(20*time.Millisecond))
+	go p.keepAlive()
+
+	Eventually(pings.Load).Should(BeNumerically(">=", 3))
+	Consistently(pings.Load, 200*time.Millisecond).Should(BeNumerically("<", 15))
+
+	// no pings while a command is in flight
+	done := make(chan error)
+	go func() { done <- p.Click("#slow") }()
+	time.Sleep(30 * time.Millisecond)
+	inFlight := pings.Load()
+	Consistently(pings.Load, 100*time.Millisecond).Should(Equal(inFlight))
+	close(release)
+	Expect(<-done).To(Succeed())
+
+	p.cancel()
+	time.Sleep(30 * time.Millisecond)
+	stopped := pings.Load()
+	Consistently(pings.Load, 100*time.Millisecond).Should(Equal(stopped))
+
+	// only the click is accounted
+	Expect(p.commandCount).To(Equal(1))
+}
+
+func TestInfoDuringKeepAlive(t *testing.T) {
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{UsedProxy: "proxy:8080", Value: "https://example.com"}, nil
+	}, WithKeepAlive(time.Millisecond))
+	go p.keepAlive()
+
+	// stats are updated by keepalive pings concurrently with Info (checked by go test -race)
+	for i := 0; i < 20; i++ {
+		info, err := p.Info()
+		Expect(err).To(BeNil())
+		Expect(info.SessionID).To(Equal("test-session"))
+		time.Sleep(time.Millisecond)
+	}
+	p.cancel()
+}
+
+func TestSetRange(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.SetRange("#volume", 75)).To(Succeed())
+	Expect(p.SetRange("#price", 12.5, WithDragToValue())).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"setRange('#volume', 75)", "setRange('#price', 12.5, 'dragToValue')"}))
+}
+
+func TestSetDate(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+	checkIn := time.Date(2024, time.March, 7, 14, 5, 59, 0, time.UTC)
+
+	Expect(p.SetDate("#check-in", checkIn)).To(Succeed())
+	Expect(p.SetDateTime("#meeting", checkIn, WithTimeout("5s"))).To(Succeed())
+	Expect(c.programs).To(Equal([]string{
+		"setDate('#check-in', '2024-03-07')",
+		"setDateTime('#meeting', '2024-03-07T14:05', 'timeout:5s')",
+	}))
+}
+
+func TestPaginate(t *testing.T) {
+	page := 1
+	signaturePolls := 0
+	loadingPolls := 0
+	signature := "evaluateJS('" + escapeJSString(pageSignatureScript) + "')"
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		switch strings.Replace(msg.Program, ", 'timeout:300ms'", "", 1) {
+		case "isElementPresent('a.next')":
+			return &dto.BrowserMessageOut{Value: page < 2}, nil
+		case "click('a.next')":
+			page++
+		case signature:
+			// content is replaced after the second poll following the click
+			signaturePolls++
+			return &dto.BrowserMessageOut{Value: fmt.Sprint(lo.Ternary(signaturePolls <= 2, 1, page))}, nil
+		case "isLoading()":
+			loadingPolls++
+			return &dto.BrowserMessageOut{Value: loadingPolls == 1}, nil
+		}
+		return &dto.BrowserMessageOut{}, nil
+	})
+
+	var extracted []int
+	pages, err := p.Paginate("a.next", 10, func(p Program) error {
+		extracted = append(extracted, page)
+		return nil
+	})
+	Expect(err).To(BeNil())
+	Expect(pages).To(Equal(2))
+	Expect(extracted).To(Equal([]int{1, 2}))
+	Expect(c.programs).To(Equal([]string{
+		"isElementPresent('a.next')", signature, "click('a.next')",
+		signature, signature, "isLoading()", signature, "isLoading()",
+		"isElementPresent('a.next')",
+	}))
+
+	page = 1
+	pages, err = p.Paginate("a.next", 1, func(p Program) error { return nil })
+	Expect(err).To(BeNil())
+	Expect(pages).To(Equal(1))
+
+	// content which never changes fails after WithTimeout
+	page, signaturePolls = 1, -100
+	_, err = p.Paginate("a.next", 10, func(p Program) error { return nil }, WithTimeout("300ms"))
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal("failed to load page 2: page was not replaced after 300ms"))
+}
+
+func TestGetCapabilities(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{
+		"browserVersion": "Chrome/124.0.6367.60",
+		"userAgent":      "Mozilla/5.0 (X11; Linux x86_64)",
+		"viewport":       map[string]any{"width": json.Number("1280"), "height": json.Number("800")},
+		"proxy":          true,
+		"recording":      false,
+	}))
+
+	capabilities, err := p.GetCapabilities()
+	Expect(err).To(BeNil())
+	Expect(capabilities).To(Equal(dto.SessionCapabilities{
+		BrowserVersion: "Chrome/124.0.6367.60",
+		UserAgent:      "Mozilla/5.0 (X11; Linux x86_64)",
+		Viewport:       dto.Viewport{Width: 1280, Height: 800},
+		Proxy:          true,
+	}))
+	Expect(c.programs).To(Equal([]string{"getCapabilities()"}))
+}
+
+func TestWaitForTextMatch(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.WaitForTextMatch(".price", `\$\d+\.\d{2}`, WithTimeout("10s"))).To(Succeed())
+	Expect(c.programs).To(Equal([]string{`waitForTextMatch('.price', '\\$\\d+\\.\\d{2}', 'timeout:10s')`}))
+
+	err := p.WaitForTextMatch(".price", `\$(\d+`)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring(`invalid pattern "\\$(\\d+"`))
+	Expect(c.programs).To(HaveLen(1))
+}
+
+func TestPaste(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.Paste("#otp", "123456")).To(Succeed())
+	Expect(p.Paste("#comment", "it's pasted", WithTimeout("5s"))).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"paste('#otp', '123456')", `paste('#comment', 'it\'s pasted', 'timeout:5s')`}))
+}
+
+func TestDefaultActionOptions(t *testing.T) {
+	p, c := newMockProgram(t, nil, WithDefaultActionOptions(WithTimeout("30s"), WithIncludeInvisible()))
+
+	Expect(p.Click("#submit")).To(Succeed())
+	Expect(p.Click("#submit", WithTimeout("5s"))).To(Succeed())
+	Expect(p.Click("#submit", WithoutTimeout())).To(Succeed())
+	Expect(c.programs).To(Equal([]string{
+		"click('#submit', 'timeout:30s','includeInvisible')",
+		"click('#submit', 'timeout:5s','includeInvisible')",
+		"click('#submit', 'withoutTimeout','includeInvisible')",
+	}))
+
+	// client-side defaults are honored and not sent to the backend
+	p, c = newMockProgram(t, nil, WithDefaultActionOptions(WithEncodeURL()))
+	Expect(p.Navigate("https://example.com/my page")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"navigate('https://example.com/my%20page')"}))
+}
+
+func TestLlmIsLoginPage(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(true))
+
+	loginPage, err := p.LlmIsLoginPage()
+	Expect(err).To(BeNil())
+	Expect(loginPage).To(BeTrue())
+	Expect(c.programs).To(Equal([]string{"llmIsLoginPage()"}))
+
+	p, _ = newMockProgram(t, valueResponse("yes"))
+	_, err = p.LlmIsLoginPage()
+	Expect(err).NotTo(BeNil())
+}
+
+func TestScrollPosition(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{"x": json.Number("0"), "y": json.Number("1250.5")}))
+
+	Expect(p.ScrollTo(0, 1250)).To(Succeed())
+	x, y, err := p.GetScrollPosition()
+	Expect(err).To(BeNil())
+	Expect(x).To(Equal(0.0))
+	Expect(y).To(Equal(1250.5))
+	Expect(c.programs).To(Equal([]string{"scrollTo(0, 1250)", "getScrollPosition()"}))
+
+	p, _ = newMockProgram(t, valueResponse("top"))
+	_, _, err = p.GetScrollPosition()
+	Expect(err).NotTo(BeNil())
+}
+
+func TestWaitForPopup(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("tab-2"))
+
+	tabID, err := p.WaitForPopup("10s")
+	Expect(err).To(BeNil())
+	Expect(tabID).To(Equal("tab-2"))
+	Expect(p.SwitchTab(tabID)).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"waitForPopup('10s')", "switchTab('tab-2')"}))
+
+	p, _ = newMockProgram(t, valueResponse(nil))
+	_, err = p.WaitForPopup("10s")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal("no popup window opened within 10s"))
+
+	p, _ = newMockProgram(t, valueResponse(map[string]any{"tab": "tab-2"}))
+	_, err = p.WaitForPopup("10s")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(HavePrefix("failed to convert value to string"))
+}
+
+func TestEvaluateJS(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("Checkout"))
+
+	value, err := p.EvaluateJS("document.title")
+	Expect(err).To(BeNil())
+	Expect(value.(*dto.BrowserMessageOut).Value).To(Equal("Checkout"))
+	Expect(c.programs).To(Equal([]string{"evaluateJS('document.title')"}))
+
+	p, _ = newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Error: "ReferenceError: foo is not defined"}, nil
+	})
+	value, err = p.EvaluateJS("foo")
+	Expect(err).NotTo(BeNil())
+	Expect(value).To(BeNil())
+}
+
+func TestExecuteInFrame(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("Checkout"))
+
+	value, err := p.ExecuteInFrame(1, "getPageTitle()", WithTimeout("5s"))
+	Expect(err).To(BeNil())
+	Expect(value).To(Equal("Checkout"))
+	Expect(c.programs).To(Equal([]string{"executeInFrame(1, 'getPageTitle()', 'timeout:5s')"}))
+
+	_, err = p.ExecuteInFrame(-1, "getPageTitle()")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal("invalid frame index -1: must be non-negative"))
+	Expect(c.programs).To(HaveLen(1))
+}
+
+func TestTitle(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("Checkout"))
+
+	title, err := p.GetTitle()
+	Expect(err).To(BeNil())
+	Expect(title).To(Equal("Checkout"))
+
+	Expect(p.SetTitle("Tab 2: Bob's cart")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"getPageTitle()", `setTitle('Tab 2: Bob\'s cart')`}))
+}
+
+func TestSelectedText(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("ACME-2024-0042"))
+
+	Expect(p.SelectText("#order-id")).To(Succeed())
+	text, err := p.GetSelectedText()
+	Expect(err).To(BeNil())
+	Expect(text).To(Equal("ACME-2024-0042"))
+	Expect(c.programs).To(Equal([]string{"selectText('#order-id')", "getSelectedText()"}))
+}
+
+func TestGetPageMeta(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{
+		"charset":      "windows-1251",
+		"language":     "ru",
+		"title":        "Новости",
+		"canonicalURL": "https://example.ru/news",
+	}))
+
+	meta, err := p.GetPageMeta()
+	Expect(err).To(BeNil())
+	Expect(meta).To(Equal(dto.PageMeta{Charset: "windows-1251", Language: "ru", Title: "Новости", CanonicalURL: "https://example.ru/news"}))
+	Expect(c.programs).To(Equal([]string{"getPageMeta()"}))
+}
+
+func TestGetImages(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse([]any{
+		map[string]any{"src": "https://example.com/logo.png", "alt": "Logo", "naturalWidth": 120, "naturalHeight": 40, "loaded": true},
+		map[string]any{"src": "https://example.com/broken.png", "alt": "", "naturalWidth": 0, "naturalHeight": 0, "loaded": false},
+	}))
+
+	images, err := p.GetImages(WithSelector("#gallery"))
+	Expect(err).To(BeNil())
+	Expect(images).To(Equal([]dto.ImageInfo{
+		{Src: "https://example.com/logo.png", Alt: "Logo", NaturalWidth: 120, NaturalHeight: 40, Loaded: true},
+		{Src: "https://example.com/broken.png"},
+	}))
+	Expect(c.programs).To(Equal([]string{"getImages('selector:#gallery')"}))
+}
+
+func TestGetElementMetrics(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{"textLength": 1840, "visibleRatio": 0.25, "inViewport": true}))
+
+	metrics, err := p.GetElementMetrics("article")
+	Expect(err).To(BeNil())
+	Expect(metrics).To(Equal(dto.ElementMetrics{TextLength: 1840, VisibleRatio: 0.25, InViewport: true}))
+	Expect(c.programs).To(Equal([]string{"getElementMetrics('article')"}))
+}
+
+func TestGetWebSocketMessages(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse([]any{
+		map[string]any{"url": "wss://chat.example.com/ws", "direction": "sent", "payload": `{"type":"subscribe"}`},
+		map[string]any{"url": "wss://chat.example.com/ws", "direction": "received", "payload": `{"type":"message","text":"hi"}`, "truncated": true},
+	}))
+
+	messages, err := p.GetWebSocketMessages(`chat\.example\.com`)
+	Expect(err).To(BeNil())
+	Expect(messages).To(Equal([]dto.WSMessage{
+		{URL: "wss://chat.example.com/ws", Direction: dto.WSDirectionSent, Payload: `{"type":"subscribe"}`},
+		{URL: "wss://chat.example.com/ws", Direction: dto.WSDirectionReceived, Payload: `{"type":"message","text":"hi"}`, Truncated: true},
+	}))
+	Expect(c.programs).To(Equal([]string{`getWebSocketMessages('chat\\.example\\.com')`}))
+
+	_, err = p.GetWebSocketMessages(`chat(`)
+	Expect(err).NotTo(BeNil())
+	Expect(c.programs).To(HaveLen(1))
+}
+
+func TestGetNetworkStats(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{
+		"requests":      42,
+		"bytesReceived": 1843200,
+		"bytesSent":     20480,
+		"byType": map[string]any{
+			"document": map[string]any{"requests": 1, "bytesReceived": 51200, "bytesSent": 1024},
+			"image":    map[string]any{"requests": 30, "bytesReceived": 1536000, "bytesSent": 15360},
+		},
+	}))
+
+	stats, err := p.GetNetworkStats()
+	Expect(err).To(BeNil())
+	Expect(stats).To(Equal(dto.NetworkStats{
+		Requests:      42,
+		BytesReceived: 1843200,
+		BytesSent:     20480,
+		ByType: map[string]dto.ResourceTypeStats{
+			"document": {Requests: 1, BytesReceived: 51200, BytesSent: 1024},
+			"image":    {Requests: 30, BytesReceived: 1536000, BytesSent: 15360},
+		},
+	}))
+	Expect(c.programs).To(Equal([]string{"getNetworkStats()"}))
+}
+
+func TestRecordWebSocketsAtStart(t *testing.T) {
+	started := startedConfig(t, Config{RecordWebSockets: true})
+	Expect(started.Browser.RecordWebSockets).To(Equal(lo.ToPtr(true)))
+
+	started = startedConfig(t, Config{})
+	Expect(started.Browser.RecordWebSockets).To(BeNil())
+}
+
+func TestMediaEmulationAtStart(t *testing.T) {
+	started := startedConfig(t, Config{ColorScheme: dto.ColorSchemeDark, ReducedMotion: true})
+	Expect(started.Browser.ColorScheme).To(Equal(dto.ColorSchemeDark))
+	Expect(started.Browser.ReducedMotion).To(Equal(lo.ToPtr(true)))
+}
+
+func TestLlmError(t *testing.T) {
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{
+			Error:       "element not found",
+			Reasoning:   "there is no button labelled Checkout, only Continue",
+			Screenshots: map[string][]byte{"result": []byte("png")},
+		}, nil
+	})
+
+	err := p.LlmClick("Checkout button")
+	var llmErr *LlmError
+	Expect(errors.As(err, &llmErr)).To(BeTrue())
+	Expect(llmErr.Screenshot).To(Equal([]byte("png")))
+	Expect(llmErr.Reasoning).To(Equal("there is no button labelled Checkout, only Continue"))
+	Expect(err.Error()).To(Equal("element not found (LLM reasoning: there is no button labelled Checkout, only Continue)"))
+
+	// screenshot is only available if the session returns screenshots
+	p, _ = newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Error: "element not found"}, nil
+	})
+	_, err = p.LlmText("order total")
+	Expect(errors.As(err, &llmErr)).To(BeTrue())
+	Expect(llmErr.Screenshot).To(BeNil())
+	Expect(err.Error()).To(Equal("element not found"))
+}
+
+func TestLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		if msg.Program == "click('#submit')" {
+			return &dto.BrowserMessageOut{Log: []string{"clicked #submit", "navigation started"}}, nil
+		}
+		return &dto.BrowserMessageOut{Log: []string{"page loaded"}}, nil
+	}, WithLogWriter(&buf))
+
+	Expect(p.Click("#submit")).To(Succeed())
+	Expect(p.WaitVisible("#done")).To(Succeed())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	Expect(lines).To(HaveLen(3))
+	Expect(lines[0]).To(MatchRegexp(`^\d{4}-\d{2}-\d{2}T\S+ \[click\('#submit'\)\] clicked #submit$`))
+	Expect(lines[1]).To(HaveSuffix(" [click('#submit')] navigation started"))
+	Expect(lines[2]).To(HaveSuffix(" [waitVisible('#done')] page loaded"))
+}
+
+func TestIsElementPresentAnyFrame(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(map[string]any{"present": true, "frame": "iframe#payment"}))
+
+	present, frame, err := p.IsElementPresentAnyFrame("#card-number")
+	Expect(err).To(BeNil())
+	Expect(present).To(BeTrue())
+	Expect(frame).To(Equal("iframe#payment"))
+	Expect(c.programs).To(Equal([]string{"isElementPresentAnyFrame('#card-number')"}))
+
+	p, _ = newMockProgram(t, valueResponse(map[string]any{"present": false}))
+	present, frame, err = p.IsElementPresentAnyFrame("#card-number")
+	Expect(err).To(BeNil())
+	Expect(present).To(BeFalse())
+	Expect(frame).To(BeEmpty())
+}
+
+func TestWaitAndGetText(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("Order #42 confirmed"))
+
+	text, err := p.WaitAndGetText(".banner", WithTimeout("10s"))
+	Expect(err).To(BeNil())
+	Expect(text).To(Equal("Order #42 confirmed"))
+	Expect(c.programs).To(HaveLen(1))
+	Expect(strings.Fields(c.programs[0])).To(Equal([]string{
+		"waitVisible('.banner',", "'timeout:10s')",
+		"getInnerText('.banner',", "'timeout:10s')",
+	}))
+}
+
+// regression: options of a call without positional arguments must not be preceded by a comma
+func TestFunctionCall0WithOptions(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse("https://example.com"))
+
+	_, err := p.GetURL(WithTimeout("5s"))
+	Expect(err).To(BeNil())
+	Expect(c.programs).To(Equal([]string{"getURL('timeout:5s')"}))
+
+	for _, tc := range []struct {
+		defaults []ActionOption
+		opts     []ActionOption
+		expected string
+	}{
+		{expected: "getURL()"},
+		{opts: []ActionOption{WithTimeout("5s"), WithIncludeInvisible()}, expected: "getURL('timeout:5s','includeInvisible')"},
+		{defaults: []ActionOption{WithTimeout("10s")}, expected: "getURL('timeout:10s')"},
+		{defaults: []ActionOption{WithTimeout("10s")}, opts: []ActionOption{WithTimeout("5s")}, expected: "getURL('timeout:5s')"},
+	} {
+		p, _ := newMockProgram(t, nil, WithDefaultActionOptions(tc.defaults...))
+		Expect(p.functionCall0("getURL", tc.opts...)).To(Equal(tc.expected))
+	}
+}
+
+func TestFunctionCallEscaping(t *testing.T) {
+	p, _ := newMockProgram(t, nil)
+
+	Expect(p.functionCall1("setTitle", "Bob's\ncart")).To(Equal(`setTitle('Bob\'s\ncart')`))
+	Expect(p.functionCall2("getAttribute", `[data-name='x']`, "title", WithSelector("it's"))).
+		To(Equal(`getAttribute('[data-name=\'x\']', 'title', 'selector:it\'s')`))
+}
+
+func TestClickIfPresent(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse(true))
+
+	clicked, err := p.ClickIfPresent("#accept-cookies")
+	Expect(err).To(BeNil())
+	Expect(clicked).To(BeTrue())
+	Expect(c.programs).To(HaveLen(1))
+	Expect(strings.Fields(c.programs[0])).To(Equal([]string{
+		"if", "(isElementPresent('#accept-cookies'))", "{", "click('#accept-cookies')", "true", "}", "else", "{", "false", "}",
+	}))
+
+	p, _ = newMockProgram(t, valueResponse(false))
+	clicked, err = p.ClickIfPresent("#accept-cookies")
+	Expect(err).To(BeNil())
+	Expect(clicked).To(BeFalse())
+}
+
+func TestGetRedirectChain(t *testing.T) {
+	p, c := newMockProgram(t, valueResponse([]any{
+		map[string]any{"url": "http://example.com", "status": json.Number("301")},
+		map[string]any{"url": "https://example.com", "status": json.Number("302")},
+		map[string]any{"url": "https://www.example.com/home", "status": json.Number("200")},
+	}))
+
+	chain, err := p.GetRedirectChain("http://example.com")
+	Expect(err).To(BeNil())
+	Expect(chain).To(Equal([]dto.RedirectHop{
+		{URL: "http://example.com", Status: 301},
+		{URL: "https://example.com", Status: 302},
+		{URL: "https://www.example.com/home", Status: 200},
+	}))
+	Expect(c.programs).To(Equal([]string{"getRedirectChain('http://example.com')"}))
+}
+
+func TestSetLocale(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.SetLocale("en-US")).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"setLocale('en-US')"}))
+}
+
+func TestLocaleAtStart(t *testing.T) {
+	started := startedConfig(t, Config{Locale: "de-DE", Languages: []string{"de-DE", "en"}})
+	Expect(started.Browser.Locale).To(Equal("de-DE"))
+	Expect(started.Browser.Languages).To(Equal([]string{"de-DE", "en"}))
+}
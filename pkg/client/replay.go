@@ -0,0 +1,97 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+// ReadTranscript reads NDJSON transcript written by WriteTranscript
+func ReadTranscript(r io.Reader) ([]TranscriptEntry, error) {
+	var entries []TranscriptEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry TranscriptEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse transcript line %d", line)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read transcript")
+	}
+	return entries, nil
+}
+
+// Replay executes programs sent by the user in the transcript one by one stopping on the first error.
+// If compare is set, result of each step must also match the result recorded in the transcript.
+// Secret placeholders recorded by WriteTranscript are resolved with secrets (values of the replaying session).
+// Returns amount of successfully replayed steps
+func Replay(p Program, entries []TranscriptEntry, secrets map[string]string, compare bool) (int, error) {
+	entries, err := resolveSecrets(entries, secrets)
+	if err != nil {
+		return 0, err
+	}
+	step := 0
+	for i, entry := range entries {
+		if entry.Role != TranscriptYou {
+			continue
+		}
+		step++
+		value, err := p.Execute(entry.Text)
+		recorded := recordedResult(entries[i+1:])
+		if err != nil {
+			if compare && recorded != nil && recorded.Role == TranscriptError && recorded.Text == err.Error() {
+				continue
+			}
+			return step - 1, errors.Wrapf(err, "step %d (%q) failed", step, entry.Text)
+		}
+		if !compare || recorded == nil {
+			continue
+		}
+		if result := fmt.Sprintf("%v", value); recorded.Role != TranscriptBrowser || recorded.Text != result {
+			return step - 1, errors.Errorf("step %d (%q) diverged: expected %s %q, got %q", step, entry.Text, recorded.Role, recorded.Text, result)
+		}
+	}
+	return step, nil
+}
+
+// recordedResult returns the first result recorded after the program (if any)
+func recordedResult(entries []TranscriptEntry) *TranscriptEntry {
+	for _, entry := range entries {
+		if entry.Role == TranscriptYou {
+			return nil
+		}
+		return &entry
+	}
+	return nil
+}
+
+var secretPlaceholderPattern = regexp.MustCompile(`\{\{secret:([^}]*)\}\}`)
+
+// resolveSecrets replaces secret placeholders with values of secrets failing if any of them isn't configured
+func resolveSecrets(entries []TranscriptEntry, secrets map[string]string) ([]TranscriptEntry, error) {
+	for _, entry := range entries {
+		for _, match := range secretPlaceholderPattern.FindAllStringSubmatch(entry.Text, -1) {
+			if secrets[match[1]] == "" {
+				return nil, errors.Errorf("secret %q used in the transcript is not configured", match[1])
+			}
+		}
+	}
+	resolver := secretsReplacer(secrets, func(name, value string) []string {
+		return []string{secretPlaceholder(name), value}
+	})
+	return lo.Map(entries, func(entry TranscriptEntry, _ int) TranscriptEntry {
+		entry.Text = resolver.Replace(entry.Text)
+		return entry
+	}), nil
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestReplay(t *testing.T) {
+	RegisterTestingT(t)
+
+	var buf bytes.Buffer
+	Expect(WriteTranscript(&buf, []TranscriptEntry{
+		{Role: TranscriptBrowser, Text: "Started session abc at https://baas.integrail.ai"},
+		{Role: TranscriptYou, Text: "navigate('https://example.com')"},
+		{Role: TranscriptBrowser, Text: "<nil>"},
+		{Role: TranscriptYou, Text: "getURL()"},
+		{Role: TranscriptBrowser, Text: "https://example.com/"},
+		{Role: TranscriptBrowser, Text: `screenshot "getURL" saved to /tmp/getURL.png`},
+	}, nil)).To(Succeed())
+	entries, err := ReadTranscript(&buf)
+	Expect(err).To(BeNil())
+
+	p, c := newMockProgram(t, valueResponse(nil))
+	steps, err := Replay(p, entries, nil, false)
+	Expect(err).To(BeNil())
+	Expect(steps).To(Equal(2))
+	Expect(c.programs).To(Equal([]string{"navigate('https://example.com')", "getURL()"}))
+
+	p, c = newMockProgram(t, valueResponse(nil))
+	steps, err = Replay(p, entries, nil, true)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal(`step 2 ("getURL()") diverged: expected browser "https://example.com/", got "<nil>"`))
+	Expect(steps).To(Equal(1))
+	Expect(c.programs).To(HaveLen(2))
+
+	// secrets are recorded as placeholders and typed with the values of the replaying session
+	buf.Reset()
+	Expect(WriteTranscript(&buf, []TranscriptEntry{
+		{Role: TranscriptYou, Text: "llmLogin('user', 'p4ssw0rd')"},
+		{Role: TranscriptBrowser, Text: "logged in"},
+	}, map[string]string{"password": "p4ssw0rd"})).To(Succeed())
+	Expect(buf.String()).NotTo(ContainSubstring("p4ssw0rd"))
+	entries, err = ReadTranscript(&buf)
+	Expect(err).To(BeNil())
+
+	p, c = newMockProgram(t, valueResponse(nil))
+	_, err = Replay(p, entries, nil, false)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal(`secret "password" used in the transcript is not configured`))
+	Expect(c.programs).To(BeEmpty())
+
+	steps, err = Replay(p, entries, map[string]string{"password": "n3w-p4ss"}, false)
+	Expect(err).To(BeNil())
+	Expect(steps).To(Equal(1))
+	Expect(c.programs).To(Equal([]string{"llmLogin('user', 'n3w-p4ss')"}))
+}
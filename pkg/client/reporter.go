@@ -0,0 +1,136 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+// ReportLevel is the minimal importance of messages passed to Reporter (see WithReportLevel)
+type ReportLevel int
+
+const (
+	ReportLevelDebug ReportLevel = iota // every message including executed commands and their results (default)
+	ReportLevelInfo                     // progress messages (e.g. saved files) and failures
+	ReportLevelError                    // failures only
+)
+
+// WithReportLevel suppresses Reporter messages below level (e.g. ReportLevelError for library users interested in failures only),
+// structured command events are not affected
+func WithReportLevel(level ReportLevel) Option {
+	return func(p *program) {
+		p.reportLevel = level
+	}
+}
+
+func (p *program) report(level ReportLevel, msg string) {
+	if level < p.reportLevel {
+		return
+	}
+	p.reporter.Report(msg)
+}
+
+// resultReportLevel reports failed results as errors and successful ones as debug messages
+func resultReportLevel(res *dto.BrowserMessageOut, err error) ReportLevel {
+	if err != nil || lo.FromPtr(res).Error != "" {
+		return ReportLevelError
+	}
+	return ReportLevelDebug
+}
+
+// maxReportedProgramLength limits how much of the program is included in error reports
+const maxReportedProgramLength = 200
+
+func truncateReported(prog string) string {
+	runes := []rune(prog)
+	if len(runes) <= maxReportedProgramLength {
+		return prog
+	}
+	return string(runes[:maxReportedProgramLength]) + "..."
+}
+
+// CommandEvent describes a single command sent to the session
+type CommandEvent struct {
+	Time       time.Time `json:"time"`             // when the command was sent
+	Command    string    `json:"command"`          // program sent to the session
+	Result     any       `json:"result,omitempty"` // value returned by the program
+	Error      string    `json:"error,omitempty"`  // error happened when executing the program
+	DurationMs int64     `json:"durationMs"`       // how long it took to execute the command
+	Cost       float64   `json:"cost"`             // cost of the command
+}
+
+// EventReporter can be implemented by Reporter to receive structured event for every executed command
+type EventReporter interface {
+	Event(event CommandEvent)
+}
+
+func reportCommandEvent(reporter Reporter, command string, started time.Time, res *dto.BrowserMessageOut, err error) {
+	eventReporter, ok := reporter.(EventReporter)
+	if !ok {
+		return
+	}
+	event := CommandEvent{
+		Time:       started,
+		Command:    command,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if res != nil {
+		event.Result = res.Value
+		event.Error = res.Error
+		event.Cost = res.Meta.Cost
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	eventReporter.Event(event)
+}
+
+type verboseReporter struct {
+	mu         sync.Mutex
+	out        io.Writer
+	jsonEvents bool
+}
+
+// NewVerboseReporter creates reporter writing every message and command event to out
+// (as JSON lines if jsonEvents is set)
+func NewVerboseReporter(out io.Writer, jsonEvents bool) Reporter {
+	return &verboseReporter{out: out, jsonEvents: jsonEvents}
+}
+
+func (r *verboseReporter) Report(msg string) {
+	if r.jsonEvents {
+		r.writeJSON(map[string]any{"time": time.Now(), "message": msg})
+		return
+	}
+	r.write(fmt.Sprintf("%s %s\n", time.Now().Format(time.TimeOnly), msg))
+}
+
+func (r *verboseReporter) Event(event CommandEvent) {
+	if r.jsonEvents {
+		r.writeJSON(event)
+		return
+	}
+	result := lo.Ternary(event.Error != "", "error: "+event.Error, fmt.Sprintf("%v", event.Result))
+	r.write(fmt.Sprintf("%s %s -> %s (%dms, cost: %f)\n", event.Time.Format(time.TimeOnly), event.Command, result, event.DurationMs, event.Cost))
+}
+
+func (r *verboseReporter) writeJSON(value any) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		r.write(fmt.Sprintf("failed to marshal verbose event: %v\n", err))
+		return
+	}
+	r.write(string(bytes) + "\n")
+}
+
+func (r *verboseReporter) write(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = io.WriteString(r.out, s)
+}
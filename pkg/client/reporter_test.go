@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func TestVerboseReporter(t *testing.T) {
+	respond := func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		res := &dto.BrowserMessageOut{Value: "https://example.com"}
+		res.Meta.Cost = 0.5
+		return res, nil
+	}
+
+	var buf bytes.Buffer
+	p, _ := newMockProgram(t, respond)
+	p.reporter = NewVerboseReporter(&buf, false)
+	_, err := p.GetURL()
+	Expect(err).To(BeNil())
+	Expect(buf.String()).To(ContainSubstring(`Executing "getURL()"...`))
+	Expect(buf.String()).To(MatchRegexp(`getURL\(\) -> https://example.com \(\d+ms, cost: 0.500000\)`))
+
+	buf.Reset()
+	p, _ = newMockProgram(t, respond)
+	p.reporter = NewVerboseReporter(&buf, true)
+	_, err = p.GetURL()
+	Expect(err).To(BeNil())
+
+	var events []CommandEvent
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event CommandEvent
+		Expect(json.Unmarshal([]byte(line), &event)).To(Succeed())
+		if event.Command != "" {
+			events = append(events, event)
+		}
+	}
+	Expect(events).To(HaveLen(1))
+	Expect(events[0].Command).To(Equal("getURL()"))
+	Expect(events[0].Result).To(Equal("https://example.com"))
+	Expect(events[0].Cost).To(Equal(0.5))
+}
+
+type recordingReporter struct {
+	messages []string
+}
+
+func (r *recordingReporter) Report(msg string) {
+	r.messages = append(r.messages, msg)
+}
+
+func TestReportLevel(t *testing.T) {
+	respond := func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		if strings.HasPrefix(msg.Program, "click") {
+			return &dto.BrowserMessageOut{Error: "element not found"}, nil
+		}
+		return &dto.BrowserMessageOut{Value: "https://example.com"}, nil
+	}
+
+	reporter := &recordingReporter{}
+	p, _ := newMockProgram(t, respond, WithReportLevel(ReportLevelError))
+	p.reporter = reporter
+	_, err := p.GetURL()
+	Expect(err).To(BeNil())
+	Expect(p.Click("#missing")).NotTo(Succeed())
+	Expect(reporter.messages).To(Equal([]string{`Got result of "click('#missing')": <nil> (element not found), <nil>`}))
+
+	// long programs are truncated
+	reporter.messages = nil
+	Expect(p.Click(strings.Repeat("x", 300))).NotTo(Succeed())
+	Expect(reporter.messages).To(Equal([]string{`Got result of "click('` + strings.Repeat("x", 193) + `...": <nil> (element not found), <nil>`}))
+
+	reporter = &recordingReporter{}
+	p, _ = newMockProgram(t, respond)
+	p.reporter = reporter
+	_, err = p.GetURL()
+	Expect(err).To(BeNil())
+	Expect(reporter.messages).To(Equal([]string{`Executing "getURL()"...`, "Got result: https://example.com (), <nil>"}))
+}
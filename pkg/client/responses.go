@@ -0,0 +1,116 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+type responseCapture struct {
+	pattern string
+	re      *regexp.Regexp
+	dir     string
+}
+
+// WithCaptureResponses enables CaptureResponses from the session start (e.g. to capture API calls of the first page load)
+func WithCaptureResponses(pattern, dir string) Option {
+	return func(p *program) {
+		p.responseCaptures = append(p.responseCaptures, responseCapture{pattern: pattern, dir: dir})
+	}
+}
+
+// CaptureResponses makes every network response with URL matching the regular expression to be saved to dir
+// for the rest of the session, files are named by hash of the URL (so repeated requests overwrite the file);
+// pattern must be compatible with both Go and JavaScript regular expressions
+func (p *program) CaptureResponses(pattern string, dir string, opts ...ActionOption) error {
+	capture := responseCapture{pattern: pattern, dir: dir}
+	if err := capture.compile(); err != nil {
+		return err
+	}
+	if _, err := p.runProgram(p.functionCall1("captureResponses", pattern, opts...)); err != nil {
+		return err
+	}
+	// responses are saved while sending (including keepalive pings), so captures are guarded by sendMu
+	p.sendMu.Lock()
+	p.responseCaptures = append(p.responseCaptures, capture)
+	p.sendMu.Unlock()
+	return nil
+}
+
+func (c *responseCapture) compile() error {
+	re, err := regexp.Compile(c.pattern)
+	if err != nil {
+		return errors.Wrapf(err, "invalid response pattern %q", c.pattern)
+	}
+	c.re = re
+	return nil
+}
+
+// compileResponseCaptures validates patterns passed with WithCaptureResponses
+func (p *program) compileResponseCaptures() error {
+	for i := range p.responseCaptures {
+		if err := p.responseCaptures[i].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *program) responseCapturePatterns() []string {
+	if len(p.responseCaptures) == 0 {
+		return nil
+	}
+	return lo.Map(p.responseCaptures, func(capture responseCapture, _ int) string {
+		return capture.pattern
+	})
+}
+
+// saveResponses writes captured network responses to the directories of matching captures
+func (p *program) saveResponses(responses []dto.NetworkResponse) {
+	for _, response := range responses {
+		for _, capture := range p.responseCaptures {
+			if capture.re == nil || !capture.re.MatchString(response.URL) {
+				continue
+			}
+			fileName := filepath.Join(capture.dir, responseFileName(response))
+			if err := os.MkdirAll(capture.dir, 0o755); err != nil {
+				p.report(ReportLevelError, fmt.Sprintf("Failed to create dir %s: %v", capture.dir, err))
+				continue
+			}
+			if err := os.WriteFile(fileName, response.Body, 0o644); err != nil {
+				p.report(ReportLevelError, fmt.Sprintf("Failed to save response of %s to %s: %v", response.URL, fileName, err))
+				continue
+			}
+			p.report(ReportLevelInfo, fmt.Sprintf("Response of %s saved to %s", response.URL, fileName))
+		}
+	}
+}
+
+func responseFileName(response dto.NetworkResponse) string {
+	hash := sha256.Sum256([]byte(response.URL))
+	return hex.EncodeToString(hash[:8]) + responseFileExt(response.ContentType)
+}
+
+func responseFileExt(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ".bin"
+	}
+	if strings.HasSuffix(mediaType, "json") {
+		return ".json"
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func TestCaptureResponses(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "responses")
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Responses: []dto.NetworkResponse{
+			{URL: "https://example.com/api/orders?page=1", Status: 200, ContentType: "application/json; charset=utf-8", Body: []byte(`{"orders":[]}`)},
+			{URL: "https://example.com/static/app.js", Status: 200, ContentType: "text/javascript", Body: []byte("console.log(1)")},
+		}}, nil
+	})
+
+	Expect(p.CaptureResponses(`/api/`, dir)).To(Succeed())
+	Expect(p.Click("#load")).To(Succeed())
+
+	Expect(c.programs).To(Equal([]string{"captureResponses('/api/')", "click('#load')"}))
+	entries, err := os.ReadDir(dir)
+	Expect(err).To(BeNil())
+	Expect(entries).To(HaveLen(1))
+	Expect(entries[0].Name()).To(HaveSuffix(".json"))
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(Equal(`{"orders":[]}`))
+
+	Expect(p.CaptureResponses(`/api/(`, dir)).NotTo(Succeed())
+	Expect(c.programs).To(HaveLen(2))
+}
+
+func TestCaptureResponsesDuringKeepAlive(t *testing.T) {
+	dir := t.TempDir()
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Responses: []dto.NetworkResponse{
+			{URL: "https://example.com/api/ping", Status: 200, ContentType: "application/json", Body: []byte(`{}`)},
+		}}, nil
+	}, WithKeepAlive(time.Millisecond))
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		p.keepAlive()
+	}()
+
+	// keepalive pings save responses concurrently with registering captures (checked by go test -race)
+	for i := 0; i < 20; i++ {
+		Expect(p.CaptureResponses(`/api/`, filepath.Join(dir, fmt.Sprint(i)))).To(Succeed())
+		time.Sleep(time.Millisecond)
+	}
+	p.cancel()
+	<-stopped
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultScreenshotTolerance is max ratio of differing pixels for screenshot to match the baseline
+const DefaultScreenshotTolerance = 0.01
+
+// pixelThreshold is max difference of a color channel (0-255) for pixels to be considered equal
+// (it absorbs compression noise and anti-aliasing)
+const pixelThreshold = 16
+
+// DefaultBaselineDir is directory of VisualCheckpoint baselines unless WithBaselineDir is passed
+const DefaultBaselineDir = "baselines"
+
+const (
+	updateBaselineArg = "updateBaseline"
+	diffToleranceArg  = "diffTolerance"
+)
+
+// WithUpdateBaseline makes AssertScreenshotMatches rewrite the baseline with the current screenshot
+// instead of comparing (it is not sent to the backend)
+func WithUpdateBaseline() ActionOption {
+	return func(args []string) []string {
+		return append(args, updateBaselineArg)
+	}
+}
+
+// WithDiffTolerance overrides max ratio of differing pixels for AssertScreenshotMatches (it is not sent to the backend)
+func WithDiffTolerance(ratio float64) ActionOption {
+	return func(args []string) []string {
+		return append(args, fmt.Sprintf("%s:%s", diffToleranceArg, strconv.FormatFloat(ratio, 'f', -1, 64)))
+	}
+}
+
+// WithBaselineDir sets directory where VisualCheckpoint keeps baselines (default: DefaultBaselineDir)
+func WithBaselineDir(dir string) Option {
+	return func(p *program) {
+		p.baselineDir = dir
+	}
+}
+
+// VisualCheckpoint compares screenshot to the baseline stored as <name>.png in the baseline directory
+// (see WithBaselineDir), if there is no baseline yet the screenshot becomes the baseline and the checkpoint passes;
+// it accepts the same options as AssertScreenshotMatches (e.g. WithDiffTolerance)
+func (p *program) VisualCheckpoint(name string, opts ...ActionOption) error {
+	dir := p.baselineDir
+	if dir == "" {
+		dir = DefaultBaselineDir
+	}
+	baselinePath := filepath.Join(dir, name+".png")
+	if _, err := os.Stat(baselinePath); err == nil {
+		return p.AssertScreenshotMatches(name, baselinePath, opts...)
+	} else if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to check baseline %s", baselinePath)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create baseline dir %s", dir)
+	}
+	return p.AssertScreenshotMatches(name, baselinePath, append(opts, WithUpdateBaseline())...)
+}
+
+// AssertScreenshotMatches takes screenshot and compares it to the baseline image,
+// on mismatch diff image is written next to the baseline (e.g. home.diff.png)
+func (p *program) AssertScreenshotMatches(name, baselinePath string, opts ...ActionOption) error {
+	_, update, opts := p.takeClientArg(updateBaselineArg, opts)
+	tolerance := DefaultScreenshotTolerance
+	toleranceValue, found, opts := p.takeClientArg(diffToleranceArg, opts)
+	if found {
+		var err error
+		if tolerance, err = strconv.ParseFloat(toleranceValue, 64); err != nil {
+			return errors.Wrapf(err, "invalid diff tolerance %q", toleranceValue)
+		}
+	}
+
+	screenshot, err := p.TakeScreenshot(name, opts...)
+	if err != nil {
+		return err
+	}
+	if update {
+		if err := os.WriteFile(baselinePath, screenshot, 0o644); err != nil {
+			return errors.Wrapf(err, "failed to update baseline %s", baselinePath)
+		}
+		p.report(ReportLevelInfo, fmt.Sprintf("baseline %s updated with %q", baselinePath, name))
+		return nil
+	}
+
+	baseline, err := os.ReadFile(baselinePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read baseline %s (use WithUpdateBaseline to create it)", baselinePath)
+	}
+	ratio, diff, err := CompareImages(baseline, screenshot)
+	if err != nil {
+		return errors.Wrapf(err, "failed to compare %q with baseline %s", name, baselinePath)
+	}
+	if ratio <= tolerance {
+		return nil
+	}
+	diffPath := strings.TrimSuffix(baselinePath, filepath.Ext(baselinePath)) + ".diff.png"
+	if err := os.WriteFile(diffPath, diff, 0o644); err != nil {
+		p.report(ReportLevelError, fmt.Sprintf("failed to save diff image to %s: %v", diffPath, err))
+	}
+	return errors.Errorf("screenshot %q differs from baseline %s by %.2f%% (tolerance: %.2f%%), diff saved to %s",
+		name, baselinePath, ratio*100, tolerance*100, diffPath)
+}
+
+// CompareImages returns ratio of differing pixels between two encoded (PNG or JPEG) images
+// and PNG image highlighting the differences in red, images of different size differ completely
+func CompareImages(expected, actual []byte) (float64, []byte, error) {
+	expectedImg, _, err := image.Decode(bytes.NewReader(expected))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to decode expected image")
+	}
+	actualImg, _, err := image.Decode(bytes.NewReader(actual))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to decode actual image")
+	}
+
+	bounds := actualImg.Bounds()
+	sameSize := expectedImg.Bounds().Size() == bounds.Size()
+	offset := expectedImg.Bounds().Min.Sub(bounds.Min)
+	diff := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	differing := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			actualColor := actualImg.At(x, y)
+			if sameSize && pixelsEqual(expectedImg.At(x+offset.X, y+offset.Y), actualColor) {
+				gray := color.GrayModel.Convert(actualColor).(color.Gray)
+				// faded original to keep context of the differences
+				diff.Set(x-bounds.Min.X, y-bounds.Min.Y, color.RGBA{R: gray.Y/4 + 191, G: gray.Y/4 + 191, B: gray.Y/4 + 191, A: 255})
+				continue
+			}
+			differing++
+			diff.Set(x-bounds.Min.X, y-bounds.Min.Y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, diff); err != nil {
+		return 0, nil, errors.Wrap(err, "failed to encode diff image")
+	}
+	if bounds.Empty() {
+		return 0, buf.Bytes(), nil
+	}
+	return float64(differing) / float64(bounds.Dx()*bounds.Dy()), buf.Bytes(), nil
+}
+
+func pixelsEqual(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	for _, pair := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+		d := int(pair[0]>>8) - int(pair[1]>>8)
+		if d > pixelThreshold || d < -pixelThreshold {
+			return false
+		}
+	}
+	return true
+}
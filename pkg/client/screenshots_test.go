@@ -0,0 +1,158 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/integrail/baas-client/pkg/client/dto"
+)
+
+func testImage(width, height, redPixels int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		if i < redPixels {
+			c = color.RGBA{R: 255, A: 255}
+		}
+		img.Set(i%width, i/width, c)
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestCompareImages(t *testing.T) {
+	RegisterTestingT(t)
+
+	ratio, _, err := CompareImages(testImage(10, 10, 0), testImage(10, 10, 0))
+	Expect(err).To(BeNil())
+	Expect(ratio).To(Equal(0.0))
+
+	ratio, diff, err := CompareImages(testImage(10, 10, 0), testImage(10, 10, 5))
+	Expect(err).To(BeNil())
+	Expect(ratio).To(Equal(0.05))
+	diffImg, err := png.Decode(bytes.NewReader(diff))
+	Expect(err).To(BeNil())
+	Expect(diffImg.Bounds().Dx()).To(Equal(10))
+
+	ratio, _, err = CompareImages(testImage(10, 10, 0), testImage(20, 10, 0))
+	Expect(err).To(BeNil())
+	Expect(ratio).To(Equal(1.0))
+}
+
+func TestAssertScreenshotMatches(t *testing.T) {
+	screenshot := testImage(10, 10, 5)
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Screenshots: map[string][]byte{"home": screenshot}}, nil
+	})
+	baseline := filepath.Join(t.TempDir(), "home.png")
+
+	Expect(p.AssertScreenshotMatches("home", baseline)).NotTo(Succeed())
+	Expect(p.AssertScreenshotMatches("home", baseline, WithUpdateBaseline())).To(Succeed())
+	Expect(p.AssertScreenshotMatches("home", baseline)).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"takeScreenshot('home')", "takeScreenshot('home')", "takeScreenshot('home')"}))
+
+	screenshot = testImage(10, 10, 0)
+	err := p.AssertScreenshotMatches("home", baseline, WithTimeout("5s"))
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("differs from baseline"))
+	Expect(err.Error()).To(ContainSubstring("by 5.00%"))
+	Expect(filepath.Join(filepath.Dir(baseline), "home.diff.png")).To(BeAnExistingFile())
+	Expect(c.programs[len(c.programs)-1]).To(Equal("takeScreenshot('home', 'timeout:5s')"))
+
+	Expect(p.AssertScreenshotMatches("home", baseline, WithDiffTolerance(0.1))).To(Succeed())
+
+	data, err := os.ReadFile(baseline)
+	Expect(err).To(BeNil())
+	Expect(data).To(Equal(testImage(10, 10, 5)))
+}
+
+func TestVisualCheckpoint(t *testing.T) {
+	screenshot := testImage(10, 10, 5)
+	dir := filepath.Join(t.TempDir(), "baselines")
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Screenshots: map[string][]byte{"checkout": screenshot}}, nil
+	}, WithBaselineDir(dir))
+
+	// first run creates the baseline
+	Expect(p.VisualCheckpoint("checkout")).To(Succeed())
+	data, err := os.ReadFile(filepath.Join(dir, "checkout.png"))
+	Expect(err).To(BeNil())
+	Expect(data).To(Equal(screenshot))
+
+	Expect(p.VisualCheckpoint("checkout")).To(Succeed())
+
+	// regression beyond tolerance fails and keeps the baseline
+	screenshot = testImage(10, 10, 50)
+	err = p.VisualCheckpoint("checkout")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("differs from baseline"))
+	Expect(filepath.Join(dir, "checkout.diff.png")).To(BeAnExistingFile())
+	data, err = os.ReadFile(filepath.Join(dir, "checkout.png"))
+	Expect(err).To(BeNil())
+	Expect(data).To(Equal(testImage(10, 10, 5)))
+
+	Expect(c.programs).To(HaveLen(3))
+}
+
+func TestSaveScreenshotFileNameTokens(t *testing.T) {
+	p, _ := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &dto.BrowserMessageOut{Screenshots: map[string][]byte{"home": []byte("png")}}, nil
+	})
+	dir := t.TempDir()
+
+	Expect(p.SaveScreenshot("home", filepath.Join(dir, "home.png"))).To(Succeed())
+	Expect(filepath.Join(dir, "home.png")).To(BeAnExistingFile())
+
+	Expect(p.SaveScreenshot("home", filepath.Join(dir, "{session}-{step}-{timestamp}.png"))).To(Succeed())
+	files, err := filepath.Glob(filepath.Join(dir, "test-session-002-*.png"))
+	Expect(err).To(BeNil())
+	Expect(files).To(HaveLen(1))
+	Expect(filepath.Base(files[0])).To(MatchRegexp(`^test-session-002-\d{8}-\d{6}\.png$`))
+
+	now := time.Date(2024, 5, 17, 9, 30, 15, 0, time.UTC)
+	Expect(p.expandFileName("{session}/{timestamp}.png", now)).To(Equal("test-session/20240517-093015.png"))
+}
+
+func TestScreenshotURLDelivery(t *testing.T) {
+	var res dto.BrowserMessageOut
+	Expect(json.Unmarshal([]byte(`{"sessionID":"s","screenshotURLs":{"home":"https://cdn.example.com/s/home.png"}}`), &res)).To(Succeed())
+	Expect(res.ScreenshotURLs).To(Equal(map[string]string{"home": "https://cdn.example.com/s/home.png"}))
+
+	p, c := newMockProgram(t, func(msg dto.BrowserMessageIn) (*dto.BrowserMessageOut, error) {
+		return &res, nil
+	})
+	c.screenshots = map[string][]byte{"https://cdn.example.com/s/home.png": []byte("png")}
+
+	// nothing is fetched until the screenshot is requested
+	Expect(p.Click("#menu")).To(Succeed())
+	Expect(c.fetched).To(BeEmpty())
+
+	screenshot, err := p.TakeScreenshot("home")
+	Expect(err).To(BeNil())
+	Expect(screenshot).To(Equal([]byte("png")))
+	Expect(c.fetched).To(Equal([]string{"https://cdn.example.com/s/home.png"}))
+}
+
+func TestSaveSnapshot(t *testing.T) {
+	mhtml := "From: <Saved by Blink>\r\nMIME-Version: 1.0\r\nContent-Type: multipart/related;\r\n\r\n<!DOCTYPE html><html></html>"
+	p, c := newMockProgram(t, valueResponse(mhtml))
+	fileName := filepath.Join(t.TempDir(), "{session}.mhtml")
+
+	Expect(p.SaveSnapshot(fileName, WithTimeout("2m"))).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"saveSnapshot('timeout:2m')"}))
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(fileName), "test-session.mhtml"))
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(HavePrefix("From: <Saved by Blink>"))
+	Expect(string(data)).To(ContainSubstring("MIME-Version: 1.0"))
+}
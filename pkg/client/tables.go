@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ExtractTable returns text of the table cells as rows (header rows included)
+func (p *program) ExtractTable(selector string, opts ...ActionOption) ([][]string, error) {
+	res, err := p.runProgram(p.functionCall1("extractTable", selector, opts...))
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	if err := decodeValue(res.Value, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ExtractTableCSV extracts the table and writes it to fileName as CSV (RFC 4180, i.e. with CRLF line breaks)
+func (p *program) ExtractTableCSV(selector, fileName string, opts ...ActionOption) error {
+	rows, err := p.ExtractTable(selector, opts...)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", fileName)
+	}
+	defer func() { _ = file.Close() }()
+	writer := csv.NewWriter(file)
+	writer.UseCRLF = true
+	if err := writer.WriteAll(rows); err != nil {
+		return errors.Wrapf(err, "failed to write %s", fileName)
+	}
+	if err := file.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write %s", fileName)
+	}
+	p.report(ReportLevelInfo, fmt.Sprintf("%d rows of %q saved to %s", len(rows), selector, fileName))
+	return nil
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestExtractTableCSV(t *testing.T) {
+	table := []any{
+		[]any{"Name", "Comment"},
+		[]any{`Widget "Pro"`, "red, large"},
+		[]any{"Gadget", "line one\nline two"},
+	}
+	p, c := newMockProgram(t, valueResponse(table))
+	fileName := filepath.Join(t.TempDir(), "products.csv")
+
+	Expect(p.ExtractTableCSV("table#products", fileName)).To(Succeed())
+	Expect(c.programs).To(Equal([]string{"extractTable('table#products')"}))
+
+	data, err := os.ReadFile(fileName)
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(Equal("Name,Comment\r\n\"Widget \"\"Pro\"\"\",\"red, large\"\r\nGadget,\"line one\r\nline two\"\r\n"))
+
+	file, err := os.Open(fileName)
+	Expect(err).To(BeNil())
+	defer func() { _ = file.Close() }()
+	rows, err := csv.NewReader(file).ReadAll()
+	Expect(err).To(BeNil())
+	Expect(rows).To(Equal([][]string{
+		{"Name", "Comment"},
+		{`Widget "Pro"`, "red, large"},
+		{"Gadget", "line one\nline two"},
+	}))
+}
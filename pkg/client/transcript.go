@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+const (
+	TranscriptYou     = "you"
+	TranscriptBrowser = "browser"
+	TranscriptError   = "error"
+)
+
+type TranscriptEntry struct {
+	Timestamp time.Time `json:"timestamp"` // when the entry was recorded
+	Role      string    `json:"role"`      // who produced the entry: you, browser or error
+	Text      string    `json:"text"`      // program sent or result received
+}
+
+// secretPlaceholder is recorded in place of the secret value, so that transcripts never contain secrets
+// while Replay can still type them (resolving placeholders with the secrets of the replaying session)
+func secretPlaceholder(name string) string {
+	return "{{secret:" + name + "}}"
+}
+
+// WriteTranscript writes entries as NDJSON (one JSON object per line) replacing values of secrets
+// with {{secret:name}} placeholders
+func WriteTranscript(w io.Writer, entries []TranscriptEntry, secrets map[string]string) error {
+	masker := secretsReplacer(secrets, func(name, value string) []string {
+		return []string{value, secretPlaceholder(name)}
+	})
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		entry.Text = masker.Replace(entry.Text)
+		if err := encoder.Encode(entry); err != nil {
+			return errors.Wrapf(err, "failed to write transcript entry")
+		}
+	}
+	return nil
+}
+
+// secretsReplacer builds replacer from the pairs returned for every non-empty secret (sorted by name to be deterministic)
+func secretsReplacer(secrets map[string]string, pair func(name, value string) []string) *strings.Replacer {
+	names := lo.Filter(lo.Keys(secrets), func(name string, _ int) bool {
+		return secrets[name] != ""
+	})
+	sort.Strings(names)
+	return strings.NewReplacer(lo.FlatMap(names, func(name string, _ int) []string {
+		return pair(name, secrets[name])
+	})...)
+}
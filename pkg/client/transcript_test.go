@@ -0,0 +1,57 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWriteTranscript(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	entries := []TranscriptEntry{
+		{Timestamp: now, Role: TranscriptYou, Text: "llmLogin('user', 'p4ssw0rd')"},
+		{Timestamp: now.Add(time.Second), Role: TranscriptBrowser, Text: "logged in with \"p4ssw0rd\"\nredirected"},
+		{Timestamp: now.Add(2 * time.Second), Role: TranscriptError, Text: "element not found"},
+	}
+
+	var buf bytes.Buffer
+	Expect(WriteTranscript(&buf, entries, map[string]string{"password": "p4ssw0rd", "empty": ""})).To(Succeed())
+
+	var lines []TranscriptEntry
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry TranscriptEntry
+		Expect(json.Unmarshal(scanner.Bytes(), &entry)).To(Succeed())
+		lines = append(lines, entry)
+	}
+	Expect(lines).To(Equal([]TranscriptEntry{
+		{Timestamp: now, Role: TranscriptYou, Text: "llmLogin('user', '{{secret:password}}')"},
+		{Timestamp: now.Add(time.Second), Role: TranscriptBrowser, Text: "logged in with \"{{secret:password}}\"\nredirected"},
+		{Timestamp: now.Add(2 * time.Second), Role: TranscriptError, Text: "element not found"},
+	}))
+}
+
+func TestRecordTranscriptConcurrently(t *testing.T) {
+	RegisterTestingT(t)
+
+	// async command handlers record responses while Update records sent programs (checked by go test -race)
+	m := &CliClient{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.record(TranscriptBrowser, "ok")
+		}()
+		m.record(TranscriptYou, "getURL()")
+	}
+	wg.Wait()
+	Expect(m.transcript).To(HaveLen(20))
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const encodeURLArg = "encodeURL"
+
+// WithEncodeURL makes navigation commands percent-encode spaces and special characters of the URL
+// instead of rejecting it (it is not sent to the backend)
+func WithEncodeURL() ActionOption {
+	return func(args []string) []string {
+		return append(args, encodeURLArg)
+	}
+}
+
+var urlCharsEncoder = strings.NewReplacer(" ", "%20", "\t", "%09", "\r", "%0D", "\n", "%0A", "'", "%27", `\`, "%5C")
+
+var urlScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// navigationTarget validates URL to navigate to (it must be absolute),
+// with WithEncodeURL spaces and special characters are percent-encoded first
+func (p *program) navigationTarget(target string, opts []ActionOption) (string, []ActionOption, error) {
+	_, encode, opts := p.takeClientArg(encodeURLArg, opts)
+	if encode {
+		target = urlCharsEncoder.Replace(strings.TrimSpace(target))
+	}
+	if !urlScheme.MatchString(target) {
+		return "", opts, errors.Errorf("invalid URL %q: missing scheme (e.g. https://)", target)
+	}
+	return target, opts, nil
+}
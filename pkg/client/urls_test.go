@@ -0,0 +1,30 @@
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNavigateValidatesURL(t *testing.T) {
+	p, c := newMockProgram(t, nil)
+
+	Expect(p.Navigate("https://example.com/search?q=shoes", WithTimeout("10s"))).To(Succeed())
+
+	err := p.Navigate("example.com/search")
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(Equal(`invalid URL "example.com/search": missing scheme (e.g. https://)`))
+
+	// URLs are passed as is, characters which would break the program are escaped
+	err = p.Navigate("https://example.com/my page?q=red shoes&by=o'neil")
+	Expect(err).To(BeNil())
+
+	_, err = p.NavigateResult("https://example.com/my page?q=red shoes&by=o'neil", WithEncodeURL())
+	Expect(err).To(BeNil())
+
+	Expect(c.programs).To(Equal([]string{
+		"navigate('https://example.com/search?q=shoes', 'timeout:10s')",
+		`navigate('https://example.com/my page?q=red shoes&by=o\'neil')`,
+		"navigateResult('https://example.com/my%20page?q=red%20shoes&by=o%27neil')",
+	}))
+}
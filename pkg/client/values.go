@@ -0,0 +1,88 @@
+package client
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// line terminators end a JS string literal, so they are escaped along with quotes and backslashes
+var jsStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\u2028", `\u2028`, "\u2029", `\u2029`)
+
+// escapeJSString escapes value so that it can be safely put into a single-quoted program argument
+func escapeJSString(value string) string {
+	return jsStringEscaper.Replace(value)
+}
+
+// jsStringArray renders values as an array of single-quoted program arguments (e.g. ['a','b'])
+func jsStringArray(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, "'"+escapeJSString(value)+"'")
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
+
+func valueToBool(value any) (bool, error) {
+	res, ok := value.(bool)
+	if !ok {
+		return false, errors.Errorf("failed to convert value to bool: %v", value)
+	}
+	return res, nil
+}
+
+func valueToString(value any) (string, error) {
+	res, ok := value.(string)
+	if !ok {
+		return "", errors.Errorf("failed to convert value to string: %v", value)
+	}
+	return res, nil
+}
+
+// valueToFloat converts numeric value (json.Number as decoded by the client or float64) to float64
+func valueToFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		res, err := v.Float64()
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to convert value to number: %v", value)
+		}
+		return res, nil
+	case float64:
+		return v, nil
+	default:
+		return 0, errors.Errorf("failed to convert value to number: %v", value)
+	}
+}
+
+// valueToInt converts numeric value (json.Number as decoded by the client or float64) to int
+// without losing precision of large integers
+func valueToInt(value any) (int, error) {
+	if number, ok := value.(json.Number); ok {
+		if res, err := number.Int64(); err == nil {
+			return int(res), nil
+		}
+	}
+	res, err := valueToFloat(value)
+	if err != nil {
+		return 0, err
+	}
+	if res != math.Trunc(res) {
+		return 0, errors.Errorf("failed to convert value to int: %v is not an integer", value)
+	}
+	return int(res), nil
+}
+
+// decodeValue converts generic value returned by the browser into the typed structure
+func decodeValue(value any, out any) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal value")
+	}
+	if err := json.Unmarshal(bytes, out); err != nil {
+		return errors.Wrapf(err, "failed to decode value: %s", string(bytes))
+	}
+	return nil
+}
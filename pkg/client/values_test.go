@@ -0,0 +1,23 @@
+package client
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestEscapeJSString(t *testing.T) {
+	RegisterTestingT(t)
+
+	for value, expected := range map[string]string{
+		`plain`:                   `plain`,
+		`Bob's`:                   `Bob\'s`,
+		`C:\temp`:                 `C:\\temp`,
+		"line 1\nline 2":          `line 1\nline 2`,
+		"line 1\r\nline 2":        `line 1\r\nline 2`,
+		"a\u2028b\u2029c":         `a\u2028b\u2029c`,
+		"it's\n\\n not a newline": `it\'s\n\\n not a newline`,
+	} {
+		Expect(escapeJSString(value)).To(Equal(expected), value)
+	}
+}